@@ -37,6 +37,11 @@ func (m *CMat) GetData() []complex128 {
 	return m.mat.RawCMatrix().Data
 }
 
+// retourne le nombre de lignes et de colonnes d'une matrice
+func (m *CMat) Dims() (int, int) {
+	return m.mat.Dims()
+}
+
 // retourne une matrice n x m dont les coefficients sont issus de data
 func NewCMat(n, m int, data []complex128) CMat {
 	ma := mat.NewCDense(n, m, data)
@@ -59,12 +64,14 @@ func (ma *CMat) getColOf(i int) int {
 	return i % c
 }
 
-// AJOUTER GESTION DERREUR
 // Retourne une copie de la ième colonne du reciever
-// la première colonne est la colonne 0
-func (ma *CMat) GetCol(i int) *CMat {
+// la première colonne est la colonne 0 ; ErrIndexOutOfRange si elle n'existe pas
+func (ma *CMat) GetCol(i int) (*CMat, error) {
 
 	r, c := ma.mat.Dims()
+	if i < 0 || i >= c {
+		return nil, fmt.Errorf("%w : column %d of a %d x %d matrix", ErrIndexOutOfRange, i, r, c)
+	}
 	resdata := make([]complex128, r)
 	j := 0
 	for k, coef := range ma.GetData() {
@@ -74,19 +81,21 @@ func (ma *CMat) GetCol(i int) *CMat {
 		}
 	}
 	res := NewCMat(r, 1, resdata)
-	return &res
+	return &res, nil
 }
 
-// AJOUTER GESTION DERREUR
 // Retourne une copie de la ième ligne du reciever
-// la première ligne est la ligne 0
-func (ma *CMat) GetRaw(i int) *CMat {
+// la première ligne est la ligne 0 ; ErrIndexOutOfRange si elle n'existe pas
+func (ma *CMat) GetRaw(i int) (*CMat, error) {
 
-	_, c := ma.mat.Dims()
+	r, c := ma.mat.Dims()
+	if i < 0 || i >= r {
+		return nil, fmt.Errorf("%w : row %d of a %d x %d matrix", ErrIndexOutOfRange, i, r, c)
+	}
 	resdata := make([]complex128, c)
 	copy(resdata, ma.GetData()[i*c:(i+1)*c])
 	res := NewCMat(1, c, resdata)
-	return &res
+	return &res, nil
 }
 
 // retourne des matrices E et F telles que reciever = E + iF
@@ -177,9 +186,23 @@ func PP(m *mat.Dense) {
 	fmt.Printf("%.2g\n\n", fc)
 }
 
+// retourne ErrDimension si les matrices <a> et <b> n'ont pas les mêmes dimensions
+func sameDims(a, b *CMat) error {
+	ra, ca := a.mat.Dims()
+	rb, cb := b.mat.Dims()
+	if ra != rb || ca != cb {
+		return fmt.Errorf("%w : %d x %d and %d x %d matrices", ErrDimension, ra, ca, rb, cb)
+	}
+	return nil
+}
+
 // place la sommedes matrices a + b dans le reciever
-func (m *CMat) Add(a, b *CMat) *CMat {
-	mR, mI := m.RI()
+// ErrDimension si a et b n'ont pas les mêmes dimensions
+func (m *CMat) Add(a, b *CMat) (*CMat, error) {
+	if err := sameDims(a, b); err != nil {
+		return m, err
+	}
+	var mR, mI mat.Dense // le reciever peut avoir d'autres dimensions
 	aR, aI := a.RI()
 	bR, bI := b.RI()
 
@@ -187,12 +210,15 @@ func (m *CMat) Add(a, b *CMat) *CMat {
 	mI.Add(&aI, &bI)
 
 	*m = Merge(&mR, &mI)
-	return m
+	return m, nil
 }
 
 // place le produit coef par coef a . b dans le reciever
-// AJOUTER GESTION ERREURS SUIVANT LES TYPES
-func (m *CMat) CoefWiseProd(a, b *CMat) *CMat {
+// ErrDimension si a et b n'ont pas les mêmes dimensions
+func (m *CMat) CoefWiseProd(a, b *CMat) (*CMat, error) {
+	if err := sameDims(a, b); err != nil {
+		return m, err
+	}
 	aR, aI := a.RI()
 	bR, bI := b.RI()
 
@@ -207,7 +233,7 @@ func (m *CMat) CoefWiseProd(a, b *CMat) *CMat {
 	u.Add(&u, &v)
 
 	*m = Merge(&t, &u)
-	return m
+	return m, nil
 }
 
 // mutiplie in place le reciever par un nombre complexe c
@@ -220,10 +246,14 @@ func (m *CMat) Scale(c complex128) *CMat {
 }
 
 // place la différence a - b dans le reciever
-func (m *CMat) Sub(a, b *CMat) *CMat {
-	mR, mI := m.RI()
+// ErrDimension si a et b n'ont pas les mêmes dimensions
+func (m *CMat) Sub(a, b *CMat) (*CMat, error) {
+	if err := sameDims(a, b); err != nil {
+		return m, err
+	}
+	var mR, mI mat.Dense // le reciever peut avoir d'autres dimensions
 	aR, aI := a.RI()
-	bR, bI := a.RI()
+	bR, bI := b.RI()
 	bR.Scale(-1, &bR)
 	bI.Scale(-1, &bI)
 
@@ -231,12 +261,18 @@ func (m *CMat) Sub(a, b *CMat) *CMat {
 	mI.Add(&aI, &bI)
 
 	*m = Merge(&mR, &mI)
-	return m
+	return m, nil
 }
 
 // place le produit matriciel a*b dans le reciever
-func (m *CMat) Mult(a, b *CMat) *CMat {
-	mR, mI := m.RI()
+// ErrDimension si le nombre de colonnes de a n'est pas le nombre de lignes de b
+func (m *CMat) Mult(a, b *CMat) (*CMat, error) {
+	ra, ca := a.mat.Dims()
+	rb, cb := b.mat.Dims()
+	if ca != rb {
+		return m, fmt.Errorf("%w : product of %d x %d and %d x %d matrices", ErrDimension, ra, ca, rb, cb)
+	}
+	var mR, mI mat.Dense // le reciever peut avoir d'autres dimensions
 	aR, aI := a.RI()
 	bR, bI := b.RI()
 
@@ -253,7 +289,7 @@ func (m *CMat) Mult(a, b *CMat) *CMat {
 	mI.Add(&ab, &ba)
 
 	*m = Merge(&mR, &mI)
-	return m
+	return m, nil
 }
 
 // transpose la matrice m in place
@@ -281,21 +317,24 @@ func (m *CMat) Conjugate() *CMat {
 	return m
 }
 
-//AJOUTER CONTROLE DES TAILLE DES VECTEURS
 // retourne le produit scalaire des matrices colonnes a et b
-func DotProduct(a, b *CMat) complex128 {
+// ErrDimension si elles n'ont pas le même nombre de coefficients
+func DotProduct(a, b *CMat) (complex128, error) {
+	if len(a.GetData()) != len(b.GetData()) {
+		return 0, fmt.Errorf("%w : vectors of sizes %d and %d", ErrDimension, len(a.GetData()), len(b.GetData()))
+	}
 	res := 0 + 0i
 	dataa, datab := a.mat.RawCMatrix().Data, b.mat.RawCMatrix().Data
 	for k, _ := range a.GetData() {
 		res += dataa[k] * cmplx.Conj(datab[k])
 	}
-	return res
+	return res, nil
 }
 
 //Renvoie le carré de la norme de la matrice colonne m
-//Ajouter le controle de la dimension
 func (m *CMat) SquaredNorm() complex128 {
-	return DotProduct(m, m)
+	res, _ := DotProduct(m, m) // m a toujours la dimension de m
+	return res
 }
 
 //Retourne ||a||_infty
@@ -317,50 +356,59 @@ func (a *CMat) MaxNorm() float64 {
 //Renvoie la projection de a sur b en arrondissant les composantes
 //Arrondi non random
 //ne modifie ni a ni b
-func ProjectOn(a, b *CMat) *CMat {
-	coef := real(DotProduct(a, b))
+// ErrDimension si a et b n'ont pas le même nombre de coefficients
+func ProjectOn(a, b *CMat) (*CMat, error) {
+	dot, err := DotProduct(a, b)
+	if err != nil {
+		return nil, err
+	}
+	coef := real(dot)
 	norm := real(b.SquaredNorm())
 	//coef := DotProduct(a, b)
 	//norm := b.SquaredNorm()
 	res := Copy(b)
 	//res.Scale(coef / norm)
 	res.Scale(complex(math.Round(coef/norm), 0))
-	return &res
+	return &res, nil
 }
 
 //retourne la somme des projections de a sur les colonnes de b
-func ProjectOnCols(a, b *CMat) *CMat {
+// ErrDimension si a n'a pas la taille des colonnes de b
+func ProjectOnCols(a, b *CMat) (*CMat, error) {
 	r, c := b.mat.Dims()
 
 	res := NewCMat(r, 1, make([]complex128, r))
 
-	var col, proj *CMat
-
 	for k := 0; k < c; k++ {
-		col = b.GetCol(k)
-		proj = ProjectOn(a, col)
+		col, _ := b.GetCol(k)
+		proj, err := ProjectOn(a, col)
+		if err != nil {
+			return nil, err
+		}
 		res.Add(&res, proj)
 	}
 
-	return &res
+	return &res, nil
 
 }
 
 //retourne la somme des projections de a sur les lignes de b
 // sortie en tant que vecteur colonne
-func ProjectOnRaws(a, b *CMat) *CMat {
+// ErrDimension si a n'a pas la taille des lignes de b
+func ProjectOnRaws(a, b *CMat) (*CMat, error) {
 	r, c := b.mat.Dims()
 	res := NewCMat(c, 1, make([]complex128, c))
 
-	var raw, proj *CMat
-
 	for k := 0; k < r; k++ {
-		raw = b.GetRaw(k)
-		proj = ProjectOn(a, raw)
+		raw, _ := b.GetRaw(k)
+		proj, err := ProjectOn(a, raw)
+		if err != nil {
+			return nil, err
+		}
 		proj.Transpose()
 		res.Add(&res, proj)
 	}
 
-	return &res
+	return &res, nil
 
 }
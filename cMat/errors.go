@@ -0,0 +1,10 @@
+package cMat
+
+import "errors"
+
+// Erreurs retournées par les fonctions du package (éventuellement enveloppées avec fmt.Errorf et %w,
+// elles se testent avec errors.Is)
+var (
+	ErrDimension       = errors.New("dimension mismatch")
+	ErrIndexOutOfRange = errors.New("index out of range")
+)
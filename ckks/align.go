@@ -0,0 +1,208 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"kazat.ch/lbcrypto/poly"
+)
+
+// Politique appliquée par les opérations arithmétiques (CTAdd, CTMultNoRelin, CTAddPlain, ...)
+// lorsque les niveaux, les modules ou les échelles de leurs opérandes diffèrent
+type AlignmentPolicy int
+
+const (
+	AlignAuto   AlignmentPolicy = iota // les opérandes sont alignés automatiquement (voir Align), politique par défaut
+	AlignStrict                        // l'opération retourne l'erreur de CheckOperands
+)
+
+// tolérance relative en dessous de laquelle deux échelles sont considérées comme égales :
+// les échelles calculées en flottants (après des RS, ou choisies exactement) peuvent différer de quelques ulp
+const scaleTolerance = 1e-9
+
+// indique si les échelles <s1> et <s2> sont égales à la tolérance près
+func sameScale(s1, s2 complex128) bool {
+	return math.Abs(real(s1)-real(s2)) <= scaleTolerance*math.Max(math.Abs(real(s1)), math.Abs(real(s2)))
+}
+
+// retourne une erreur si <ct1> et <ct2> ne sont pas au même niveau ou n'ont pas le même module
+func checkLevels(ct1, ct2 CT) error {
+	if ct1.L != ct2.L {
+		return fmt.Errorf("%w : %d and %d", ErrLevelMismatch, ct1.L, ct2.L)
+	}
+	if ct1.Mod.Cmp(ct2.Mod) != 0 {
+		return fmt.Errorf("%w : different moduli at level %d", ErrLevelMismatch, ct1.L)
+	}
+	return nil
+}
+
+// retourne une erreur si <ct1> et <ct2> ne peuvent pas être additionnés tels quels :
+// ErrLevelMismatch si leurs niveaux ou leurs modules diffèrent, ErrScaleMismatch si leurs échelles diffèrent
+func (ckks *CKKS) CheckOperands(ct1, ct2 CT) error {
+	if err := checkLevels(ct1, ct2); err != nil {
+		return err
+	}
+	if !sameScale(ct1.Scale, ct2.Scale) {
+		return fmt.Errorf("%w : %e and %e", ErrScaleMismatch, real(ct1.Scale), real(ct2.Scale))
+	}
+	return nil
+}
+
+// retourne <ct1> et <ct2> ramenés au même niveau et à la même échelle :
+//   - l'opérande de plus haut niveau est restreint au niveau de l'autre ; si les échelles diffèrent,
+//     il est multiplié par une constante ramenant son échelle à celle de l'autre (il consomme un de ses niveaux en trop)
+//   - au même niveau, si le rapport des échelles est entier, l'opérande de plus petite échelle est multiplié
+//     par cet entier (CTIncScale) sans consommer de niveau, si son échelle reste inférieure à q_L/2
+//   - sinon, <ct2> est ramené à l'échelle de <ct1> par une constante et un niveau est consommé
+//
+// ErrLevelMismatch si les opérandes ont le même niveau mais des modules différents,
+// ErrLevelExhausted s'il faut consommer un niveau au niveau 0,
+// ErrScaleMismatch si les échelles sont trop éloignées pour être corrigées par une constante (voir checkScaleCorrection)
+// ou par un entier (voir checkScaleIncrease)
+func (ckks *CKKS) Align(ct1, ct2 CT) (CT, CT, error) {
+
+	if ct1.L == ct2.L && ct1.Mod.Cmp(ct2.Mod) != 0 {
+		return CT{}, CT{}, checkLevels(ct1, ct2)
+	}
+
+	if sameScale(ct1.Scale, ct2.Scale) {
+		if ct1.L > ct2.L {
+			ct1 = ckks.dropToLevel(ct1, ct2.L)
+		} else if ct2.L > ct1.L {
+			ct2 = ckks.dropToLevel(ct2, ct1.L)
+		}
+		return ct1, ct2, nil
+	}
+
+	switch {
+	case ct1.L > ct2.L:
+		if err := ckks.checkScaleCorrection(ct1, ct2.L, real(ct2.Scale)); err != nil {
+			return CT{}, CT{}, err
+		}
+		ct1 = ckks.multConstTo(ct1, 1, ct2.L, real(ct2.Scale))
+	case ct2.L > ct1.L:
+		if err := ckks.checkScaleCorrection(ct2, ct1.L, real(ct1.Scale)); err != nil {
+			return CT{}, CT{}, err
+		}
+		ct2 = ckks.multConstTo(ct2, 1, ct1.L, real(ct1.Scale))
+	default:
+		if k, ok := integerRatio(ct1.Scale, ct2.Scale); ok {
+			if err := checkScaleIncrease(ct2, k); err != nil {
+				return CT{}, CT{}, err
+			}
+			ct2.incScale(k)
+		} else if k, ok := integerRatio(ct2.Scale, ct1.Scale); ok {
+			if err := checkScaleIncrease(ct1, k); err != nil {
+				return CT{}, CT{}, err
+			}
+			ct1.incScale(k)
+		} else {
+			if ct1.L == 0 {
+				return CT{}, CT{}, fmt.Errorf("%w : cannot align the scales at level 0", ErrLevelExhausted)
+			}
+			if err := ckks.checkScaleCorrection(ct2, ct1.L-1, real(ct1.Scale)); err != nil {
+				return CT{}, CT{}, err
+			}
+			ct2 = ckks.multConstTo(ct2, 1, ct1.L-1, real(ct1.Scale))
+			ct1 = ckks.dropToLevel(ct1, ct1.L-1)
+		}
+	}
+	return ct1, ct2, nil
+}
+
+// retourne ErrScaleMismatch si <ct> ne peut pas être ramené à l'échelle <scale> au niveau <level> par multConstTo :
+// la constante k = round(scale * q_{level+1} / ct.Scale) doit être au moins 1 (arrondie à 0, elle annulerait le message)
+// et l'échelle k * ct.Scale avant le RS doit rester inférieure au module q_0 * ... * q_{level+1} (sinon le message déborde)
+func (ckks *CKKS) checkScaleCorrection(ct CT, level int, scale float64) error {
+	mod, _ := new(big.Float).SetInt(poly.ProdModuli(ckks.Moduli[:level+2])).Float64()
+	k := math.Round(scale * float64(ckks.Moduli[level+1]) / real(ct.Scale))
+	if !(k >= 1 && k*real(ct.Scale) < mod) {
+		return fmt.Errorf("%w : cannot bring the scale %e to %e at level %d", ErrScaleMismatch, real(ct.Scale), scale, level)
+	}
+	return nil
+}
+
+// retourne ErrScaleMismatch si l'échelle de <ct> multipliée par l'entier <k> (voir CTIncScale)
+// n'est pas inférieure à q_L/2, où q_L est le module de <ct> (sinon le message déborde)
+func checkScaleIncrease(ct CT, k *big.Int) error {
+	half, _ := new(big.Float).SetInt(new(big.Int).Rsh(ct.Mod, 1)).Float64()
+	if !(real(ct.Scale)*float64(k.Int64()) < half) {
+		return fmt.Errorf("%w : cannot multiply the scale %e by %d at level %d", ErrScaleMismatch, real(ct.Scale), k, ct.L)
+	}
+	return nil
+}
+
+// retourne k = <s1>/<s2> si c'est un entier >= 2 (à la tolérance près) tenant sur un int64
+func integerRatio(s1, s2 complex128) (*big.Int, bool) {
+	ratio := real(s1) / real(s2)
+	k := math.Round(ratio)
+	if k < 2 || k > math.MaxInt64/2 || math.Abs(ratio-k) > scaleTolerance*ratio {
+		return nil, false
+	}
+	return big.NewInt(int64(k)), true
+}
+
+// retourne les opérandes d'une addition, alignés selon la politique de <ckks> s'ils sont incompatibles
+// ErrLevelMismatch si l'un d'eux n'est pas un ciphertext de <ckks> (voir checkDefined)
+func (ckks *CKKS) addOperands(ct1, ct2 CT) (CT, CT, error) {
+	if err := ckks.checkDefined(ct1, ct2); err != nil {
+		return CT{}, CT{}, err
+	}
+	err := ckks.CheckOperands(ct1, ct2)
+	if err == nil {
+		return ct1, ct2, nil
+	}
+	if ckks.Alignment == AlignStrict {
+		return CT{}, CT{}, err
+	}
+	return ckks.Align(ct1, ct2)
+}
+
+// retourne les opérandes d'une multiplication (dont les échelles peuvent différer),
+// ramenés au même niveau selon la politique de <ckks>
+func (ckks *CKKS) multOperands(ct1, ct2 CT) (CT, CT, error) {
+	if err := ckks.checkDefined(ct1, ct2); err != nil {
+		return CT{}, CT{}, err
+	}
+	err := checkLevels(ct1, ct2)
+	if err == nil {
+		return ct1, ct2, nil
+	}
+	if ckks.Alignment == AlignStrict || ct1.L == ct2.L {
+		return CT{}, CT{}, err
+	}
+	if ct1.L > ct2.L {
+		return ckks.dropToLevel(ct1, ct2.L), ct2, nil
+	}
+	return ct1, ckks.dropToLevel(ct2, ct1.L), nil
+}
+
+// retourne <ct> ramené à l'échelle <scale> d'un plaintext à ajouter, selon la politique de <ckks>
+// (par un entier si le rapport des échelles en est un, par une constante en consommant un niveau sinon)
+// ErrScaleMismatch si les échelles sont trop éloignées (voir checkScaleCorrection et checkScaleIncrease)
+func (ckks *CKKS) plainOperand(ct CT, scale complex128) (CT, error) {
+	if err := ckks.checkDefined(ct); err != nil {
+		return CT{}, err
+	}
+	if sameScale(ct.Scale, scale) {
+		return ct, nil
+	}
+	if ckks.Alignment == AlignStrict {
+		return CT{}, fmt.Errorf("%w : %e and %e", ErrScaleMismatch, real(ct.Scale), real(scale))
+	}
+	if k, ok := integerRatio(scale, ct.Scale); ok {
+		if err := checkScaleIncrease(ct, k); err != nil {
+			return CT{}, err
+		}
+		ct.incScale(k)
+		return ct, nil
+	}
+	if ct.L == 0 {
+		return CT{}, fmt.Errorf("%w : cannot align the scales at level 0", ErrLevelExhausted)
+	}
+	if err := ckks.checkScaleCorrection(ct, ct.L-1, real(scale)); err != nil {
+		return CT{}, err
+	}
+	return ckks.multConstTo(ct, 1, ct.L-1, real(scale)), nil
+}
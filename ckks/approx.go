@@ -0,0 +1,161 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+)
+
+// Approximations de fonctions non polynomiales sur des ciphertexts
+
+// retourne un ciphertext chiffrant une approximation de 1/x, où x est le message (réel) de <ct>,
+// supposé dans l'intervalle <interval> = [a, b] avec 0 < a < b ; le résultat est à l'échelle de <ct>
+// méthode de Goldschmidt : avec t = 1 - x/b, 1/x = 1/b * (1 + t)(1 + t^2)(1 + t^4)...
+// après <iterations> facteurs, l'erreur relative est (1 - a/b)^(2^iterations) ;
+// <iterations> + 1 niveaux sont consommés (voir InverseDepth)
+func (ckks *CKKS) CTInverse(ct CT, interval [2]float64, iterations int, evk SwitchingKey) (CT, error) {
+
+	if iterations < 1 {
+		return CT{}, fmt.Errorf("%w : at least one iteration is needed", ErrInvalidArgument)
+	}
+	if err := ckks.checkApprox(ct, interval, InverseDepth(iterations), evk); err != nil {
+		return CT{}, err
+	}
+
+	b := interval[1]
+	scale := real(ct.Scale)
+	level := ct.L - 1
+
+	// échelles des t_i = t^(2^i), puis échelles des y_i choisies pour que le résultat soit à l'échelle de <ct>
+	// t_i est au niveau level - i, y_i = y_(i-1) * (1 + t_i) est calculé au niveau level - i puis divisé par q_(level-i)
+	scalesT := make([]float64, iterations)
+	scalesT[0] = scale
+	for i := 1; i < iterations; i++ {
+		scalesT[i] = scalesT[i-1] * scalesT[i-1] / float64(ckks.Moduli[level-i+1])
+	}
+	scalesY := make([]float64, iterations)
+	scalesY[iterations-1] = scale
+	for i := iterations - 1; i > 0; i-- {
+		scalesY[i-1] = scalesY[i] * float64(ckks.Moduli[level-i]) / scalesT[i]
+	}
+
+	t := ckks.multConstTo(ct, complex(-1/b, 0), level, scalesT[0])
+	t = ckks.addConst(t, 1)
+	y := ckks.multConstTo(ct, complex(-1/(b*b), 0), level, scalesY[0])
+	y = ckks.addConst(y, complex(2/b, 0))
+
+	for i := 1; i < iterations; i++ {
+		t = ckks.mult(t, t, evk)
+		ckks.rescale(&t)
+		t.Scale = complex(scalesT[i], 0)
+
+		y = ckks.mult(ckks.dropToLevel(y, t.L), ckks.addConst(t, 1), evk)
+		ckks.rescale(&y)
+		y.Scale = complex(scalesY[i], 0)
+	}
+
+	return y, nil
+}
+
+// retourne une erreur si <ct> ne peut pas être l'argument d'une approximation sur <interval> = [a, b]
+// consommant <depth> niveaux : ErrInvalidArgument si on n'a pas 0 < a < b, ErrNotRelinearized,
+// ErrLevelExhausted ou ErrMissingKey
+func (ckks *CKKS) checkApprox(ct CT, interval [2]float64, depth int, evk SwitchingKey) error {
+	if a, b := interval[0], interval[1]; a <= 0 || a >= b {
+		return fmt.Errorf("%w : the interval must be [a, b] with 0 < a < b", ErrInvalidArgument)
+	}
+	if err := ckks.checkDegree(ct); err != nil {
+		return err
+	}
+	if ct.L < depth {
+		return fmt.Errorf("%w : the approximation needs %d levels, the ciphertext has %d", ErrLevelExhausted, depth, ct.L)
+	}
+	if !ckks.isSwitchingKey(evk) {
+		return fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
+	return nil
+}
+
+// retourne le nombre de niveaux consommés par CTInverse avec <iterations> facteurs
+func InverseDepth(iterations int) int {
+	if iterations == 1 {
+		return 1
+	}
+	return iterations + 1
+}
+
+// degré de l'approximation initiale de 1/sqrt(x) utilisée par CTInvSqrt
+// sur [a, b] avec b/a <= 1000, elle est positive et à moins de 65% de 1/sqrt(x), ce qui garantit la convergence de Newton
+const invSqrtGuessDegree = 7
+
+// retourne un ciphertext chiffrant une approximation de 1/sqrt(x), où x est le message (réel) de <ct>,
+// supposé dans l'intervalle <interval> = [a, b] avec 0 < a < b ; le résultat est à l'échelle de <ct>
+// une approximation de Chebyshev de degré 7 est raffinée par <iterations> itérations de Newton
+// y <- 1.5*y - 0.5*x*y^3, qui élèvent l'erreur relative au carré (à un facteur 1.5 près) ;
+// InvSqrtDepth(iterations) niveaux sont consommés
+func (ckks *CKKS) CTInvSqrt(ct CT, interval [2]float64, iterations int, evk SwitchingKey) (CT, error) {
+	if iterations < 0 {
+		return CT{}, fmt.Errorf("%w : negative number of iterations", ErrInvalidArgument)
+	}
+	if err := ckks.checkApprox(ct, interval, InvSqrtDepth(iterations), evk); err != nil {
+		return CT{}, err
+	}
+	return ckks.invSqrt(ct, interval, iterations, evk), nil
+}
+
+// comme CTInvSqrt, pour des arguments valides
+func (ckks *CKKS) invSqrt(ct CT, interval [2]float64, iterations int, evk SwitchingKey) CT {
+
+	a, b := interval[0], interval[1]
+	scale := real(ct.Scale)
+	guess := chebyshevApproximation(func(x float64) float64 { return 1 / math.Sqrt(x) }, invSqrtGuessDegree, a, b)
+	y := ckks.evalPoly(ct, guess, evk)
+
+	for i := 0; i < iterations; i++ {
+		// -x/2 est encodé à l'échelle qui ramène (-x/2 * y) * y^2 à l'échelle de y après les deux RS
+		qa, qb := float64(ckks.Moduli[y.L]), float64(ckks.Moduli[y.L-1])
+		xHalf := ckks.multConstTo(ct, -0.5, y.L, qa*qa*qb/(scale*scale))
+
+		xy := ckks.mult(xHalf, y, evk)
+		ckks.rescale(&xy)
+		y2 := ckks.mult(y, y, evk)
+		ckks.rescale(&y2)
+		xy3 := ckks.mult(xy, y2, evk)
+		ckks.rescale(&xy3)
+		xy3.Scale = complex(scale, 0)
+
+		y = ckks.add(xy3, ckks.multConstTo(y, 1.5, xy3.L, scale))
+	}
+
+	return y
+}
+
+// retourne un ciphertext chiffrant une approximation de sqrt(x), calculée comme x * 1/sqrt(x) (voir CTInvSqrt)
+// le résultat est à l'échelle de <ct> ; SqrtDepth(iterations) niveaux sont consommés
+func (ckks *CKKS) CTSqrt(ct CT, interval [2]float64, iterations int, evk SwitchingKey) (CT, error) {
+
+	if iterations < 0 {
+		return CT{}, fmt.Errorf("%w : negative number of iterations", ErrInvalidArgument)
+	}
+	if err := ckks.checkApprox(ct, interval, SqrtDepth(iterations), evk); err != nil {
+		return CT{}, err
+	}
+
+	y := ckks.invSqrt(ct, interval, iterations, evk)
+	x := ckks.multConstTo(ct, 1, y.L, float64(ckks.Moduli[y.L]))
+
+	res := ckks.mult(x, y, evk)
+	ckks.rescale(&res)
+	res.Scale = ct.Scale
+
+	return res, nil
+}
+
+// retourne le nombre de niveaux consommés par CTInvSqrt avec <iterations> itérations de Newton
+func InvSqrtDepth(iterations int) int {
+	return optimalDepth(invSqrtGuessDegree) + 1 + 2*iterations
+}
+
+// retourne le nombre de niveaux consommés par CTSqrt avec <iterations> itérations de Newton
+func SqrtDepth(iterations int) int {
+	return InvSqrtDepth(iterations) + 1
+}
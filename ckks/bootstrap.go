@@ -0,0 +1,367 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"sort"
+
+	"kazat.ch/lbcrypto/poly"
+)
+
+// Bootstrapping (Cheon, Han, Kim, Kim et Song) : un ciphertext de niveau 0 est ramené à un niveau élevé
+//  1. ModRaise : le ciphertext modulo q_0 est vu modulo Q, il chiffre alors t + q_0 * I où t est le message
+//     et I un polynôme à petits coefficients (|I| <= K)
+//  2. CoeffsToSlots : les coefficients de t + q_0 * I sont placés dans les slots de deux ciphertexts
+//  3. EvalMod : la réduction modulo q_0 est approchée par x -> q_0/2pi * sin(2pi * x/q_0)
+//  4. SlotsToCoeffs : les coefficients réduits sont replacés dans les coefficients
+//
+// Précision : l'approximation du sinus ajoute une erreur relative d'environ (2pi * eps)^2 / 6, où eps = |t|/q_0 ;
+// avec q_0 de 60 bits, un message de norme <= 1 encodé à l'échelle 2^45 et des premiers de 45 bits,
+// la précision obtenue sur les tests (N = 64) est d'environ 2^-15 sur chaque slot
+
+// Paramètres de l'approximation de la réduction modulaire utilisée par le bootstrapping
+type BootstrappingParameters struct {
+	K           int // borne sur les coefficients de I après ModRaise (dépend du poids H de la sk)
+	Degree      int // degré de l'approximation de Chebyshev du cosinus
+	DoubleAngle int // nombre d'applications de cos(2x) = 2cos(x)^2 - 1 après l'approximation
+}
+
+// Clés nécessaires au bootstrapping
+type BootstrappingKeys struct {
+	Evk  SwitchingKey // evaluation key (relinéarisation)
+	Cjk  SwitchingKey // clé de conjugaison
+	Rtks RotationKeys // clés de rotation des transformations linéaires (voir bootstrappingRotations)
+}
+
+// retourne des paramètres adaptés à une sk de poids H <= 64
+func DefaultBootstrappingParameters() BootstrappingParameters {
+	return BootstrappingParameters{K: 12, Degree: 31, DoubleAngle: 3}
+}
+
+// retourne ErrInvalidArgument si on n'a pas K >= 0, Degree >= 1 et DoubleAngle >= 0
+func (params BootstrappingParameters) check() error {
+	if params.K < 0 || params.Degree < 1 || params.DoubleAngle < 0 {
+		return fmt.Errorf("%w : bootstrapping parameters need K >= 0, Degree >= 1 and DoubleAngle >= 0", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// retourne le nombre de niveaux consommés par un bootstrapping :
+// 2 pour CoeffsToSlots, ceil(log2(Degree + 1)) pour l'approximation, DoubleAngle, et 1 pour SlotsToCoeffs
+func (params BootstrappingParameters) Depth() int {
+	return 2 + optimalDepth(params.Degree) + params.DoubleAngle + 1
+}
+
+// retourne les clés de bootstrapping liées à la sk
+// seules les rotations des baby steps et giant steps de CoeffsToSlots et SlotsToCoeffs ont une clé,
+// soit environ 2*sqrt(N/2) clés de rotation
+func (ckks *CKKS) BootstrappingKeyGen(sk [2]poly.RNSPoly) BootstrappingKeys {
+
+	rtks := RotationKeys{}
+	for _, k := range ckks.bootstrappingRotations() {
+		rtks[k] = ckks.RotationKeyGen(sk, k)
+	}
+
+	keys := BootstrappingKeys{
+		Evk:  ckks.EvKeyGen(sk),
+		Cjk:  ckks.ConjugationKeyGen(sk),
+		Rtks: rtks,
+	}
+	return keys
+}
+
+// retourne un ciphertext chiffrant le même message que <ct>, au niveau L - params.Depth()
+// <ct> est ramené au niveau 0 s'il ne l'est pas déjà ; son échelle est conservée
+// le message de <ct> doit être petit devant q_0 (typiquement une norme <= 1 à une échelle q_0 / 2^15)
+// ErrInvalidArgument si <params> est invalide, ErrLevelExhausted si la chaîne de modules est trop courte pour <params>,
+// ErrMissingKey si une clé manque
+func (ckks *CKKS) Bootstrap(ct CT, params BootstrappingParameters, keys BootstrappingKeys) (CT, error) {
+
+	if err := params.check(); err != nil {
+		return CT{}, err
+	}
+	if ckks.L < params.Depth() {
+		return CT{}, fmt.Errorf("%w : bootstrapping needs %d levels, the scheme has %d", ErrLevelExhausted, params.Depth(), ckks.L)
+	}
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	if err := ckks.checkBootstrappingKeys(keys); err != nil {
+		return CT{}, err
+	}
+
+	scale := real(ct.Scale)
+	raised := ckks.modRaise(ct)
+	ctRe, ctIm := ckks.coeffsToSlots(raised, params, keys)
+	ctRe = ckks.evalMod(ctRe, params, keys.Evk)
+	ctIm = ckks.evalMod(ctIm, params, keys.Evk)
+	res := ckks.slotsToCoeffs(ctRe, ctIm, scale, keys)
+
+	return res, nil
+}
+
+// retourne ErrMissingKey si l'une des clés de <keys> manque (les rotations peuvent être décomposées en puissances de 2)
+func (ckks *CKKS) checkBootstrappingKeys(keys BootstrappingKeys) error {
+	if !ckks.isSwitchingKey(keys.Evk) {
+		return fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
+	if !ckks.isSwitchingKey(keys.Cjk) {
+		return fmt.Errorf("%w : conjugation key", ErrMissingKey)
+	}
+	return ckks.checkRotations(keys.Rtks, ckks.bootstrappingRotations()...)
+}
+
+// retourne les décalages des rotations effectuées par les transformations linéaires de CoeffsToSlots et
+// SlotsToCoeffs (voir EncodedMatrix.Rotations) : leurs matrices U^H et U n'ont aucun coefficient nul,
+// leurs diagonales et donc leurs baby steps et giant steps ne dépendent que de N
+func (ckks *CKKS) bootstrappingRotations() []int {
+	U := ckks.specialFFTMatrix()
+	n := len(U)
+	UH := make([][]complex128, n)
+	for j := range UH {
+		UH[j] = make([]complex128, n)
+		for k := range UH[j] {
+			UH[j][k] = cmplx.Conj(U[k][j])
+		}
+	}
+	set := map[int]bool{}
+	for _, M := range [][][]complex128{UH, U} {
+		ks := diagonalIndices(diagonals(M, n))
+		for _, k := range bsgsRotations(ks, babySteps(ks, n)) {
+			set[k] = true
+		}
+	}
+	res := make([]int, 0, len(set))
+	for k := range set {
+		res = append(res, k)
+	}
+	sort.Ints(res)
+	return res
+}
+
+// retourne le ciphertext <ct>, ramené au niveau 0, vu modulo Q au niveau L
+// il se déchiffre en t + q_0 * I, où t est le polynôme déchiffré de <ct> et I un polynôme à petits coefficients
+func (ckks *CKKS) ModRaise(ct CT) (CT, error) {
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	return ckks.modRaise(ct), nil
+}
+
+// comme ModRaise, pour un ciphertext de degré 1
+func (ckks *CKKS) modRaise(ct CT) CT {
+
+	a := ckks.modRaisePoly(ct.A)
+	b := ckks.modRaisePoly(ct.B)
+
+	res := NewCT(a, b, ckks.Q, ct.Scale, ckks.L)
+	return res
+}
+
+// retourne le représentant centré modulo q_0 du polynôme <pol>, en forme NTT sur la base q_0, ..., q_L
+func (ckks *CKKS) modRaisePoly(pol poly.RNSPoly) poly.RNSPoly {
+	limb := poly.DropLimbs(pol, 0)
+	return ckks.toRNS(limb.ToPoly().Coefs, ckks.Moduli)
+}
+
+// retourne deux ciphertexts dont les slots contiennent les coefficients t_0, ..., t_(N/2-1)
+// et t_(N/2), ..., t_(N-1) du polynôme déchiffré de <ct>, divisés par q_0 * (K + 1)
+// les slots de <ct> sont z = 1/scale * U * (t_0 + i*t_(N/2), ...), où U[j][k] = xi^(5^j * k) :
+// on applique (2/N) * U^H (U est orthogonale à un facteur N/2 près), puis on sépare parties réelle et imaginaire
+// à l'aide d'une conjugaison
+// les slots de <ct> sont de l'ordre de q_0 * K / scale : pour que les diagonales soient encodées avec une précision
+// suffisante, elles sont encodées à l'échelle de deux premiers et deux niveaux sont consommés
+func (ckks *CKKS) CoeffsToSlots(ct CT, params BootstrappingParameters, keys BootstrappingKeys) (CT, CT, error) {
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, CT{}, err
+	}
+	if ct.L < 2 {
+		return CT{}, CT{}, fmt.Errorf("%w : CoeffsToSlots needs 2 levels, the ciphertext has %d", ErrLevelExhausted, ct.L)
+	}
+	if err := ckks.checkBootstrappingKeys(keys); err != nil {
+		return CT{}, CT{}, err
+	}
+	ctRe, ctIm := ckks.coeffsToSlots(ct, params, keys)
+	return ctRe, ctIm, nil
+}
+
+// comme CoeffsToSlots, pour des arguments valides
+func (ckks *CKKS) coeffsToSlots(ct CT, params BootstrappingParameters, keys BootstrappingKeys) (CT, CT) {
+
+	q0 := float64(ckks.Moduli[0])
+	factor := real(ct.Scale) / (q0 * 2 * float64(params.K+1))
+	U := ckks.specialFFTMatrix()
+	n := len(U)
+	M := make([][]complex128, n)
+	for j := range M {
+		M[j] = make([]complex128, n)
+		for k := range M[j] {
+			M[j][k] = complex(2*factor/float64(ckks.N), 0) * cmplx.Conj(U[k][j])
+		}
+	}
+
+	scale := float64(ct.B.Moduli[ct.L])
+	w := ckks.linearTransform(ct, M, scale, 2, keys.Rtks)
+	wConj := ckks.conjugate(w, keys.Cjk)
+
+	ctRe := ckks.add(w, wConj)
+	ctIm := ckks.multMonomial(ckks.ctSub(w, wConj), -1i)
+
+	return ctRe, ctIm
+}
+
+// retourne un ciphertext dont les slots contiennent sin(2pi * x), où x est tel que
+// les slots de <ct> contiennent x / (K + 1) ; x doit vérifier |x| <= K + 1/4
+// on approche cos(2pi * (x - 1/4) / 2^r) par un polynôme de Chebyshev en (x - 1/4) / (K + 1),
+// puis on applique r fois la formule de l'angle double
+func (ckks *CKKS) EvalMod(ct CT, params BootstrappingParameters, evk SwitchingKey) (CT, error) {
+	if err := params.check(); err != nil {
+		return CT{}, err
+	}
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	if depth := cosineApproximation(params).Depth() + params.DoubleAngle; ct.L < depth {
+		return CT{}, fmt.Errorf("%w : EvalMod needs %d levels, the ciphertext has %d", ErrLevelExhausted, depth, ct.L)
+	}
+	if !ckks.isSwitchingKey(evk) {
+		return CT{}, fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
+	return ckks.evalMod(ct, params, evk), nil
+}
+
+// retourne l'approximation de cos(2pi * (K + 1) * u / 2^r) sur [-1, 1] utilisée par EvalMod
+func cosineApproximation(params BootstrappingParameters) Polynomial {
+	omega := 2 * math.Pi * float64(params.K+1) / math.Pow(2, float64(params.DoubleAngle))
+	return chebyshevApproximation(func(u float64) float64 { return math.Cos(omega * u) }, params.Degree, -1, 1)
+}
+
+// comme EvalMod, pour des arguments valides
+func (ckks *CKKS) evalMod(ct CT, params BootstrappingParameters, evk SwitchingKey) CT {
+
+	K := float64(params.K + 1)
+	cosine := cosineApproximation(params)
+
+	u := ckks.addConst(ct, complex(-0.25/K, 0))
+	res := ckks.evalPoly(u, cosine, evk)
+
+	for i := 0; i < params.DoubleAngle; i++ {
+		res = ckks.mult(res, res, evk)
+		ckks.rescale(&res)
+		res.CTScale(big.NewInt(2))
+		res = ckks.addConst(res, -1)
+	}
+
+	return res
+}
+
+// retourne le ciphertext à l'échelle <scale> dont le polynôme déchiffré a pour coefficients
+// q_0/2pi * (s_0 + i*s'_0, ...) où s et s' sont les slots de <ctRe> et <ctIm> (sorties de EvalMod)
+// un niveau est consommé
+func (ckks *CKKS) SlotsToCoeffs(ctRe, ctIm CT, scale float64, keys BootstrappingKeys) (CT, error) {
+	if err := ckks.checkDegree(ctRe, ctIm); err != nil {
+		return CT{}, err
+	}
+	ctRe, ctIm, err := ckks.addOperands(ctRe, ctIm)
+	if err != nil {
+		return CT{}, err
+	}
+	if ctRe.L < 1 {
+		return CT{}, fmt.Errorf("%w : SlotsToCoeffs needs one level", ErrLevelExhausted)
+	}
+	if err := ckks.checkRotations(keys.Rtks, ckks.bootstrappingRotations()...); err != nil {
+		return CT{}, err
+	}
+	return ckks.slotsToCoeffs(ctRe, ctIm, scale, keys), nil
+}
+
+// comme SlotsToCoeffs, pour des arguments valides
+func (ckks *CKKS) slotsToCoeffs(ctRe, ctIm CT, scale float64, keys BootstrappingKeys) CT {
+
+	q0 := float64(ckks.Moduli[0])
+	factor := q0 / (2 * math.Pi * scale)
+	U := ckks.specialFFTMatrix()
+	for j := range U {
+		for k := range U[j] {
+			U[j][k] *= complex(factor, 0)
+		}
+	}
+
+	ct := ckks.add(ctRe, ckks.multMonomial(ctIm, 1i))
+	res := ckks.linearTransform(ct, U, scale, 1, keys.Rtks)
+
+	return res
+}
+
+// retourne la matrice U[j][k] = xi^(5^j * k), 0 <= j, k < N/2, telle que les slots d'un plaintext
+// de coefficients m soient U * (m_k + i*m_(k+N/2))_k / scale
+func (ckks *CKKS) specialFFTMatrix() [][]complex128 {
+	n := ckks.N / 2
+	M := 2 * ckks.N
+	U := make([][]complex128, n)
+	g := 1
+	for j := range U {
+		U[j] = make([]complex128, n)
+		for k := range U[j] {
+			e := (g * k) % M
+			U[j][k] = cmplx.Rect(1, 2*math.Pi*float64(e)/float64(M))
+		}
+		g = (5 * g) % M
+	}
+	return U
+}
+
+// retourne le ciphertext dont les slots sont M * z, où z sont les slots de <ct> et M la matrice carrée <M>
+// les diagonales sont encodées à l'échelle des <levels> derniers premiers de <ct> :
+// le résultat est au niveau ct.L - <levels> et à l'échelle <scale>
+func (ckks *CKKS) linearTransform(ct CT, M [][]complex128, scale float64, levels int, rtks RotationKeys) CT {
+
+	qL := 1.0
+	for i := 0; i < levels; i++ {
+		qL *= float64(ct.B.Moduli[ct.L-i])
+	}
+	n := ckks.N / 2
+	em := ckks.encodeDiagonals(diagonals(M, n), n, n, ct.L, levels, scale*qL/real(ct.Scale))
+
+	res := ckks.evalEncodedMatrix(ct, em, rtks)
+	res.Scale = complex(scale, 0)
+	return res
+}
+
+// retourne le produit du ciphertext <ct> par la constante <c> in {1, -1, i, -i}, i.e. par un monôme ±X^(N/2) :
+// le produit est exact, aucun niveau n'est consommé
+func (ckks *CKKS) multMonomial(ct CT, c complex128) CT {
+
+	m := ckks.constToRNS(c, 1, ct.B.Moduli)
+	a := poly.MultModRNS(ct.A, m)
+	b := poly.MultModRNS(ct.B, m)
+
+	res := NewCT(a, b, ct.Mod, ct.Scale, ct.L)
+	return res
+}
+
+// retourne la différence des ciphertexts de degré 1 <ct1> et <ct2>
+func (ckks *CKKS) ctSub(ct1, ct2 CT) CT {
+	neg := NewCT(poly.NegRNS(ct2.A), poly.NegRNS(ct2.B), ct2.Mod, ct2.Scale, ct2.L)
+	return ckks.add(ct1, neg)
+}
+
+// retourne c * <ct>, au niveau <level> et à l'échelle <scale> : la constante est encodée
+// de sorte que l'échelle après le RS soit exactement <scale> ; <ct> doit être au moins au niveau <level> + 1
+func (ckks *CKKS) multConstTo(ct CT, c complex128, level int, scale float64) CT {
+
+	ct = ckks.dropToLevel(ct, level+1)
+	qL := float64(ckks.Moduli[level+1])
+	m := ckks.constToRNS(c, scale*qL/real(ct.Scale), ct.B.Moduli)
+	a := poly.MultModRNS(ct.A, m)
+	b := poly.MultModRNS(ct.B, m)
+
+	res := NewCT(a, b, ct.Mod, complex(scale*qL, 0), ct.L)
+	if ct.Degree() == 2 {
+		res.C = poly.MultModRNS(ct.C, m)
+	}
+	ckks.rescale(&res)
+	return res
+}
@@ -1,6 +1,7 @@
 package ckks
 
 import (
+	"fmt"
 	"math"
 	"math/big"
 
@@ -9,42 +10,133 @@ import (
 	"kazat.ch/lbcrypto/random"
 )
 
-// A MODIFIER : IL FAUDRAIT LUI DONNER UN Q_0 ET UN DELTA, LE CKKS SE CALCULE SON Q TOUT SEUL
 // Contitent les paramètres du schéma nécessaires à l'exécution des différentes procédures
 // Sert essentiellement à éviter l'utilisation de variables globales.
+// Le module Q est une chaîne de premiers q_0 * q_1 * ... * q_L tenant sur un mot machine,
+// et les polynômes sont représentés en RNS (un limb par premier)
+// Les clés et les ciphertexts sont gardés en forme NTT : les produits se font coefficient par coefficient
 type CKKS struct {
-	N     int       // for the X^N + 1
-	Q     *big.Int  // initial coef modulus
-	P     *big.Int  // for modulus in evaluation key
-	Cyclo poly.Poly // the cyclotomic polynomial for the underlying ring (créé à partir de N)
-	L     int       //number of levels
-	H     int       // for the HWT distribution
-	s2    float64   //variance of the DG distribution
+	N             int      // for the X^N + 1
+	Q             *big.Int // initial coef modulus (produit des premiers de Moduli)
+	P             *big.Int // for modulus in evaluation key (produit des premiers de SpecialModuli)
+	Moduli        []uint64 // chaîne q_0, q_1, ..., q_L : q_0 porte le message, q_1...q_L ~ delta servent au RS
+	SpecialModuli []uint64 // premiers spéciaux p_0, ..., p_k utilisés pour les clés d'évaluation
+	Dnum          int      // nombre de chiffres de la décomposition de Q pour le key switching hybride
+	L             int      //number of levels
+	H             int      // for the HWT distribution
+	s2            float64  //variance of the DG distribution
+
+	Alignment AlignmentPolicy         // comportement des opérations dont les opérandes ont des niveaux ou des échelles différents
+	matrices  *matrixCache            // matrices encodées par LinearTransform, partagées par les copies de l'instance
 }
 
-// Représente un ciphertext (B, A)
+// Représente un ciphertext (B, A), déchiffré en B + A*s
+// Un ciphertext de degré 2 (B, A, C), produit d'une multiplication non relinéarisée, est déchiffré en B + A*s + C*s^2
 // Mélange des notations de l'article original sur CKKS et des autres sources... à modifier
 type CT struct {
-	A     poly.Poly
-	B     poly.Poly
+	A     poly.RNSPoly
+	B     poly.RNSPoly
+	C     poly.RNSPoly // composante en s^2, vide (C.Coefs == nil) pour un ciphertext de degré 1
 	Mod   *big.Int
 	Scale complex128
-	L     int // current level
+	L     int    // current level
+	Seed  []byte // graine dont A est tiré (voir EncryptSK), nil si A n'est pas tiré d'une graine
 }
 
 // retourne un objet de type ckks contenant tous les paramètres d'une instance du schéma
-func NewCKKS(Q, P *big.Int, N, H, L int, s2 float64) CKKS {
-	//creating the cyclotomic polynomial
-	cyclo := poly.ZeroPoly(N)
-	cyclo.Coefs[0].Set(big.NewInt(1))
-	cyclo.Coefs[N].Set(big.NewInt(1))
+// la chaîne de modules est formée d'un premier q_0 de <q0NbBits> bits et de <L> premiers de <deltaNbBits> bits
+// le key switching n'utilise qu'un chiffre (dnum = 1) : P est alors plus grand que Q
+// la sécurité des paramètres n'est pas vérifiée (voir NewCKKSFromParameters et EstimatedSecurity)
+// ErrInvalidArgument si les paramètres ne décrivent pas une instance du schéma (voir checkParameters)
+func NewCKKS(N, H, L, q0NbBits, deltaNbBits int, s2 float64) (CKKS, error) {
+	return NewCKKSWithDnum(N, H, L, q0NbBits, deltaNbBits, 1, s2)
+}
+
+// comme NewCKKS, mais la chaîne q_0, ..., q_L est découpée en <dnum> chiffres pour le key switching hybride
+// P est choisi comme produit de premiers de 60 bits plus grand que le plus grand chiffre :
+// un grand dnum donne un P plus petit (donc plus de sécurité à N fixé) mais des clés plus grosses
+func NewCKKSWithDnum(N, H, L, q0NbBits, deltaNbBits, dnum int, s2 float64) (CKKS, error) {
+	return newCKKS(N, H, L, q0NbBits, deltaNbBits, dnum, 60, s2)
+}
+
+// retourne ErrInvalidArgument si les paramètres ne décrivent pas une instance du schéma : N doit être
+// une puissance de 2 entre 4 et 2^30, L >= 0, dnum entre 1 et L+1, H entre 1 et N, s2 > 0,
+// et les premiers doivent avoir entre 2 et 61 bits (3 pour les premiers spéciaux)
+func checkParameters(N, H, L, q0NbBits, deltaNbBits, dnum, pNbBits int, s2 float64) error {
+	switch {
+	case N < 4 || N > 1<<30 || N&(N-1) != 0:
+		return fmt.Errorf("%w : N must be a power of 2 between 4 and 2^30, got %d", ErrInvalidArgument, N)
+	case q0NbBits < 2 || q0NbBits > 61 || deltaNbBits < 2 || deltaNbBits > 61 || pNbBits < 3 || pNbBits > 61:
+		return fmt.Errorf("%w : primes must have between 2 and 61 bits (3 for the special primes)", ErrInvalidArgument)
+	case L < 0 || dnum < 1 || dnum > L+1:
+		return fmt.Errorf("%w : L must be >= 0 and dnum between 1 and L+1", ErrInvalidArgument)
+	case H < 1 || H > N || !(s2 > 0):
+		return fmt.Errorf("%w : H must be between 1 and N and s2 positive", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// comme NewCKKSWithDnum, les premiers spéciaux ayant <pNbBits> bits
+// ErrInvalidArgument si les paramètres sont invalides ou s'il n'y a pas assez de premiers des tailles demandées
+func newCKKS(N, H, L, q0NbBits, deltaNbBits, dnum, pNbBits int, s2 float64) (CKKS, error) {
+
+	if err := checkParameters(N, H, L, q0NbBits, deltaNbBits, dnum, pNbBits, s2); err != nil {
+		return CKKS{}, err
+	}
+
+	moduli, err := poly.GeneratePrimes(q0NbBits, N, 1, nil)
+	if err != nil {
+		return CKKS{}, err
+	}
+	deltaModuli, err := poly.GeneratePrimes(deltaNbBits, N, L, moduli)
+	if err != nil {
+		return CKKS{}, err
+	}
+	moduli = append(moduli, deltaModuli...)
+
+	res := CKKS{
+		N:      N,
+		Q:      poly.ProdModuli(moduli),
+		Moduli: moduli,
+		Dnum:   dnum,
+		H:      H,
+		L:      L,
+		s2:     s2,
+
+		matrices: newMatrixCache(),
+	}
+
+	maxDigitBits := 0
+	for _, digit := range res.digits(L) {
+		if bits := poly.ProdModuli(moduli[digit[0]:digit[1]]).BitLen(); bits > maxDigitBits {
+			maxDigitBits = bits
+		}
+	}
+	nbSpecial := (maxDigitBits + pNbBits - 2) / (pNbBits - 1) // chaque premier spécial fait au moins pNbBits - 1 bits
+	if res.SpecialModuli, err = poly.GeneratePrimes(pNbBits, N, nbSpecial, moduli); err != nil {
+		return CKKS{}, err
+	}
+	res.P = poly.ProdModuli(res.SpecialModuli)
+
+	return res, nil
+}
+
+// Clés de rotation, indexées par le décalage (en nombre de slots) qu'elles permettent
+type RotationKeys map[int]SwitchingKey
 
-	CKKS := CKKS{N: N, Q: Q, P: P, H: H, Cyclo: cyclo, L: L, s2: s2}
-	return CKKS
+// Secret key (1, s), définie modulo Q * P
+// les fonctions prenant un [2]poly.RNSPoly acceptent directement une SecretKey
+type SecretKey [2]poly.RNSPoly
+
+// Public key (B, A) = (-a*s + e, a), définie modulo Q
+type PublicKey struct {
+	B    poly.RNSPoly
+	A    poly.RNSPoly
+	Seed []byte // graine dont A est tiré (voir uniformNTT), nil si A n'est pas tiré d'une graine
 }
 
 // retourne un ciphertext (b, a) de module, échelle et niveau (mod, scale, L)
-func NewCT(a, b poly.Poly, mod *big.Int, scale complex128, L int) CT {
+func NewCT(a, b poly.RNSPoly, mod *big.Int, scale complex128, L int) CT {
 
 	modulus := new(big.Int).Set(mod) // pour que différents ct crés avec le même mod ne le partagent pas !
 
@@ -52,230 +144,881 @@ func NewCT(a, b poly.Poly, mod *big.Int, scale complex128, L int) CT {
 	return CT
 }
 
-// retourne une secret key (1, s)
-func (ckks *CKKS) SKeyGen() [2]poly.Poly {
-
-	c1 := poly.NewPoly([]*big.Int{big.NewInt(1)})
-	c2 := poly.NewPoly(random.Hwt(ckks.N, ckks.H))
+// retourne le degré du reciever : 1 pour (B, A), 2 pour (B, A, C)
+func (ct *CT) Degree() int {
+	if ct.C.Coefs == nil {
+		return 1
+	}
+	return 2
+}
 
-	sk := [2]poly.Poly{c1, c2}
-	return sk
+// retourne la base RNS Q ∪ P sur laquelle sont définies les clés
+func (ckks *CKKS) modulusQP() []uint64 {
+	moduli := make([]uint64, 0, len(ckks.Moduli)+len(ckks.SpecialModuli))
+	return append(append(moduli, ckks.Moduli...), ckks.SpecialModuli...)
 }
 
-// retourne une public key (-as + e, a) liée à la sk
-func (ckks *CKKS) PKeyGen(sk [2]poly.Poly) [2]poly.Poly {
+// retourne la représentation RNS en forme NTT sur la base <moduli> du polynôme à petits coefficients <coefs>
+func (ckks *CKKS) toRNS(coefs []*big.Int, moduli []uint64) poly.RNSPoly {
+	return poly.NTT(poly.ToRNS(poly.NewPoly(coefs), ckks.N, moduli))
+}
 
-	//r := ckks.r
-	s := sk[1]
-	a := random.RandomPol(ckks.N, ckks.Q)
-	e := poly.NewPoly(random.DG(ckks.N, ckks.s2, ckks.Q))
+// retourne un polynôme uniforme sur la base <moduli>, directement considéré en forme NTT
+// (la NTT d'un polynôme uniforme est uniforme), et la nouvelle graine dont il est tiré : gardée à côté du polynôme
+// dans la clé (PublicKey.Seed, SwitchingKeyDigit.Seed), elle seule est sérialisée (voir SwitchingKey.MarshalBinary)
+func (ckks *CKKS) uniformNTT(moduli []uint64) (poly.RNSPoly, []byte) {
+	seed := random.NewSeed()
+	return expandSeed(ckks.N, seed, moduli), seed
+}
 
-	b := poly.MultMod(a, s, ckks.Cyclo)
-	b.Scale(big.NewInt(-1))
-	b = poly.Add(b, e)
+// retourne une secret key (1, s), définie modulo Q * P
+func (ckks *CKKS) SKeyGen() SecretKey {
 
-	a.TakeCoefMod(ckks.Q)
-	b.TakeCoefMod(ckks.Q)
+	moduli := ckks.modulusQP()
+	c1 := ckks.toRNS([]*big.Int{big.NewInt(1)}, moduli)
+	c2 := ckks.toRNS(random.Hwt(ckks.N, ckks.H), moduli)
 
-	pk := [2]poly.Poly{b, a}
+	sk := SecretKey{c1, c2}
+	return sk
+}
 
-	return pk
+// retourne une public key (-as + e, a) liée à la sk, où a est tiré d'une graine (voir uniformNTT)
+func (ckks *CKKS) PKeyGen(sk [2]poly.RNSPoly) PublicKey {
+	a, seed := ckks.uniformNTT(ckks.Moduli)
+	return ckks.pKeyGen(sk, a, seed)
 }
 
-// retourne une evaluation key liée à la sk
-func (ckks *CKKS) EvKeyGen(sk [2]poly.Poly) [2]poly.Poly {
+// comme PKeyGen, avec le polynôme uniforme <a> défini modulo Q et tiré de la graine <seed>
+func (ckks *CKKS) pKeyGen(sk [2]poly.RNSPoly, a poly.RNSPoly, seed []byte) PublicKey {
 
-	s := sk[1]
-	prod := new(big.Int).Mul(ckks.Q, ckks.P)
-	a := random.RandomPol(ckks.N, prod)
-	e := poly.NewPoly(random.DG(ckks.N, ckks.s2, ckks.Q))
+	s := poly.DropLimbs(sk[1], ckks.L)
+	e := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), ckks.Moduli)
 
-	b := poly.MultMod(a, s, ckks.Cyclo)
-	b.Scale(big.NewInt(-1))
-	b = poly.Add(b, e)
+	b := poly.NegRNS(poly.MultModRNS(a, s))
+	b = poly.AddRNS(b, e)
 
-	s2 := poly.MultMod(s, s, ckks.Cyclo)
-	s2.Scale(ckks.P)
+	pk := PublicKey{B: b, A: a, Seed: seed}
 
-	b = poly.Add(b, s2)
+	return pk
+}
 
-	a.TakeCoefMod(prod)
-	b.TakeCoefMod(prod)
+// retourne une evaluation key liée à la sk, définie modulo Q * P
+// c'est une clé de key switching de s^2 vers s
+func (ckks *CKKS) EvKeyGen(sk [2]poly.RNSPoly) SwitchingKey {
 
-	evk := [2]poly.Poly{b, a}
+	s2 := poly.MultModRNS(sk[1], sk[1])
+	evk := ckks.switchingKeyGen(s2, sk)
 
 	return evk
 }
 
 // retourne un ciphertext chiffrant le plaintext pt à l'aide de la clé pk
-func (ckks *CKKS) Encrypt(pt encoder.PT, pk [2]poly.Poly) CT {
-
-	v := poly.NewPoly(random.ZO(ckks.N, 0.5))
-	e0 := poly.NewPoly(random.DG(ckks.N, ckks.s2, ckks.Q))
-	e1 := poly.NewPoly(random.DG(ckks.N, ckks.s2, ckks.Q))
+func (ckks *CKKS) Encrypt(pt encoder.PT, pk PublicKey) CT {
 
-	res0 := poly.MultMod(pk[0], v, ckks.Cyclo)
-	res0 = poly.Add(res0, pt.Pol)
-	res0 = poly.Add(res0, e0)
+	v := ckks.toRNS(random.ZO(ckks.N, 0.5), ckks.Moduli)
+	e0 := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), ckks.Moduli)
+	e1 := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), ckks.Moduli)
+	m := poly.NTT(poly.ToRNS(pt.Pol, ckks.N, ckks.Moduli))
 
-	res1 := poly.MultMod(pk[1], v, ckks.Cyclo)
-	res1 = poly.Add(res1, e1)
+	res0 := poly.MultModRNS(pk.B, v)
+	res0 = poly.AddRNS(res0, m)
+	res0 = poly.AddRNS(res0, e0)
 
-	res0.TakeCoefMod(ckks.Q)
-	res1.TakeCoefMod(ckks.Q)
+	res1 := poly.MultModRNS(pk.A, v)
+	res1 = poly.AddRNS(res1, e1)
 
 	CT := NewCT(res1, res0, ckks.Q, pt.Scale, ckks.L)
 
 	return CT
 }
 
+// retourne un ciphertext (-a*s + m + e, a) chiffrant le plaintext <pt> à l'aide de la clé <sk>
+// un seul produit de polynômes et une seule erreur sont nécessaires, contre deux et trois pour Encrypt
+// a est tiré d'une graine aléatoire de random.SeedSize octets, gardée dans ct.Seed : le ciphertext peut
+// être transmis avec la graine à la place de A, et reconstruit par CTFromSeed
+func (ckks *CKKS) EncryptSK(pt encoder.PT, sk [2]poly.RNSPoly) CT {
+
+	seed := random.NewSeed()
+	a := expandSeed(ckks.N, seed, ckks.Moduli)
+	s := poly.DropLimbs(sk[1], ckks.L)
+	e := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), ckks.Moduli)
+	m := ckks.ptToRNS(pt, ckks.Moduli)
+
+	b := poly.NegRNS(poly.MultModRNS(a, s))
+	b = poly.AddRNS(b, m)
+	b = poly.AddRNS(b, e)
+
+	CT := NewCT(a, b, ckks.Q, pt.Scale, ckks.L)
+	CT.Seed = seed
+	return CT
+}
+
+// retourne le polynôme de degré < <N> uniforme sur la base <moduli> tiré de la graine <seed>, considéré
+// en forme NTT ; sa restriction aux premiers q_0, ..., q_l est celui tiré pour q_0, ..., q_l
+func expandSeed(N int, seed []byte, moduli []uint64) poly.RNSPoly {
+	a := random.RandomRNSPolFrom(N, moduli, random.NewPRNG(seed))
+	a.IsNTT = true
+	return a
+}
+
+// retourne le ciphertext (<b>, a) de niveau b.Level() et d'échelle <scale>, où a est tiré de la graine <seed>
+// comme par EncryptSK : c'est l'inverse de la compression d'un ciphertext en (B, Seed)
+// ErrInvalidArgument si la graine n'a pas random.SeedSize octets ou si <b> n'est pas défini sur q_0, ..., q_l
+func (ckks *CKKS) CTFromSeed(seed []byte, b poly.RNSPoly, scale complex128) (CT, error) {
+	if len(seed) != random.SeedSize {
+		return CT{}, fmt.Errorf("%w : the seed must have %d bytes, got %d", ErrInvalidArgument, random.SeedSize, len(seed))
+	}
+	level := b.Level()
+	if level < 0 || level > ckks.L || !equalModuli(b.Moduli, ckks.Moduli[:level+1]) ||
+		len(b.Coefs) != level+1 || len(b.Coefs[0]) != ckks.N {
+		return CT{}, fmt.Errorf("%w : the polynomial is not defined on a prefix of the moduli chain", ErrInvalidArgument)
+	}
+	seed = append([]byte{}, seed...)
+	a := expandSeed(ckks.N, seed, b.Moduli)
+	b.IsNTT = true
+
+	CT := NewCT(a, b, b.Modulus(), scale, level)
+	CT.Seed = seed
+	return CT, nil
+}
+
+// indique si <a> et <b> sont les mêmes listes de premiers
+func equalModuli(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // retourne un plaintext correspondant au ciphertext ct à l'aide de la clé sk
-func (ckks *CKKS) Decrypt(ct CT, sk [2]poly.Poly) encoder.PT {
+func (ckks *CKKS) Decrypt(ct CT, sk [2]poly.RNSPoly) encoder.PT {
 
-	pt := poly.MultMod(ct.A, sk[1], ckks.Cyclo)
-	pt = poly.Add(pt, ct.B)
-	pt.TakeCoefMod(ct.Mod)
+	s := poly.DropLimbs(sk[1], ct.L)
+	pt := poly.MultModRNS(ct.A, s)
+	pt = poly.AddRNS(pt, ct.B)
+	if ct.Degree() == 2 {
+		pt = poly.AddRNS(pt, poly.MultModRNS(ct.C, poly.MultModRNS(s, s)))
+	}
 
-	res := encoder.PT{Pol: pt, Scale: ct.Scale}
+	res := encoder.PT{Pol: pt.ToPoly(), Scale: ct.Scale}
 	return res
 }
 
-// returns de sum of cyphertexts ct1 and ct2
-// A AJOUTER : VERIFICATION QUE LE SCALING FACTOR EST LE MEME
-func (ckks *CKKS) CTAdd(ct1, ct2 CT) CT {
+// returns de sum of cyphertexts ct1 and ct2, of degree 1 or 2
+// si leurs niveaux ou leurs échelles diffèrent, ils sont alignés ou l'erreur de CheckOperands est retournée
+// selon ckks.Alignment
+func (ckks *CKKS) CTAdd(ct1, ct2 CT) (CT, error) {
+	ct1, ct2, err := ckks.addOperands(ct1, ct2)
+	if err != nil {
+		return CT{}, err
+	}
+	return ckks.add(ct1, ct2), nil
+}
+
+// retourne la somme de <ct1> et <ct2>, produits par les algorithmes de la bibliothèque à la même échelle :
+// l'opérande de plus haut niveau est restreint au niveau de l'autre, quelle que soit ckks.Alignment ;
+// les fonctions publiques alignent (ou rejettent) les ciphertexts de l'utilisateur avant d'appeler add
+func (ckks *CKKS) add(ct1, ct2 CT) CT {
+
+	if ct1.L > ct2.L {
+		ct1 = ckks.dropToLevel(ct1, ct2.L)
+	} else if ct2.L > ct1.L {
+		ct2 = ckks.dropToLevel(ct2, ct1.L)
+	}
+	a := poly.AddRNS(ct1.A, ct2.A)
+	b := poly.AddRNS(ct1.B, ct2.B)
+
+	sum := NewCT(a, b, a.Modulus(), ct1.Scale, a.Level())
+	switch {
+	case ct1.Degree() == 2 && ct2.Degree() == 2:
+		sum.C = poly.AddRNS(ct1.C, ct2.C)
+	case ct1.Degree() == 2:
+		sum.C = poly.DropLimbs(ct1.C, sum.L)
+	case ct2.Degree() == 2:
+		sum.C = poly.DropLimbs(ct2.C, sum.L)
+	}
+
+	return sum
+}
+
+// retourne ErrDimension si le polynôme du plaintext <pt> n'est pas de degré < N (plaintext d'un encodeur d'un autre N)
+func (ckks *CKKS) checkPlaintext(pt encoder.PT) error {
+	if len(pt.Pol.Coefs) > ckks.N {
+		return fmt.Errorf("%w : plaintext of %d coefficients for N = %d", ErrDimension, len(pt.Pol.Coefs), ckks.N)
+	}
+	return nil
+}
+
+// retourne le polynôme du plaintext <pt> en forme NTT sur la base <moduli>
+func (ckks *CKKS) ptToRNS(pt encoder.PT, moduli []uint64) poly.RNSPoly {
+	return poly.NTT(poly.ToRNS(pt.Pol, ckks.N, moduli))
+}
+
+// retourne le polynôme en forme NTT sur la base <moduli> encodant le vecteur constant (c, ..., c) à l'échelle <scale>
+// comme xi^(5^j * N/2) = i pour tout slot j, c'est round(Re(c)*scale) + round(Im(c)*scale) * X^(N/2)
+func (ckks *CKKS) constToRNS(c complex128, scale float64, moduli []uint64) poly.RNSPoly {
+	pol := poly.ZeroPoly(ckks.N - 1)
+	pol.Coefs[0], _ = big.NewFloat(math.Round(real(c) * scale)).Int(nil)
+	pol.Coefs[ckks.N/2], _ = big.NewFloat(math.Round(imag(c) * scale)).Int(nil)
+	return poly.NTT(poly.ToRNS(pol, ckks.N, moduli))
+}
+
+// retourne la somme du ciphertext <ct> (de degré 1 ou 2) et du plaintext <pt>
+// le plaintext doit avoir la même échelle que le ciphertext (sinon voir ckks.Alignment)
+func (ckks *CKKS) CTAddPlain(ct CT, pt encoder.PT) (CT, error) {
+
+	if err := ckks.checkPlaintext(pt); err != nil {
+		return CT{}, err
+	}
+	ct, err := ckks.plainOperand(ct, pt.Scale)
+	if err != nil {
+		return CT{}, err
+	}
+	b := poly.AddRNS(ct.B, ckks.ptToRNS(pt, ct.B.Moduli))
+
+	sum := NewCT(ct.A, b, ct.Mod, ct.Scale, ct.L)
+	sum.C = ct.C
+	return sum, nil
+}
+
+// retourne la différence du ciphertext <ct> et du plaintext <pt>
+// le plaintext doit avoir la même échelle que le ciphertext (sinon voir ckks.Alignment)
+func (ckks *CKKS) CTSubPlain(ct CT, pt encoder.PT) (CT, error) {
+
+	if err := ckks.checkPlaintext(pt); err != nil {
+		return CT{}, err
+	}
+	ct, err := ckks.plainOperand(ct, pt.Scale)
+	if err != nil {
+		return CT{}, err
+	}
+	b := poly.SubRNS(ct.B, ckks.ptToRNS(pt, ct.B.Moduli))
+
+	diff := NewCT(ct.A, b, ct.Mod, ct.Scale, ct.L)
+	diff.C = ct.C
+	return diff, nil
+}
+
+// retourne le produit (slot par slot) du ciphertext <ct> et du plaintext <pt>
+// aucune clé n'est nécessaire, l'échelle du résultat est le produit des échelles
+// ErrLevelMismatch si <ct> n'est pas un ciphertext de <ckks> (voir checkDefined), ErrDimension si <pt> n'est pas de degré < N
+func (ckks *CKKS) CTMultPlain(ct CT, pt encoder.PT) (CT, error) {
+	if err := ckks.checkDefined(ct); err != nil {
+		return CT{}, err
+	}
+	if err := ckks.checkPlaintext(pt); err != nil {
+		return CT{}, err
+	}
+	return ckks.multPlain(ct, pt), nil
+}
 
-	a := poly.Add(ct1.A, ct2.A)
-	b := poly.Add(ct1.B, ct2.B)
+// comme CTMultPlain, pour des arguments valides
+func (ckks *CKKS) multPlain(ct CT, pt encoder.PT) CT {
 
-	a.TakeCoefMod(ct1.Mod)
-	b.TakeCoefMod(ct1.Mod)
+	m := ckks.ptToRNS(pt, ct.B.Moduli)
+	a := poly.MultModRNS(ct.A, m)
+	b := poly.MultModRNS(ct.B, m)
+
+	prod := NewCT(a, b, ct.Mod, ct.Scale*pt.Scale, ct.L)
+	if ct.Degree() == 2 {
+		prod.C = poly.MultModRNS(ct.C, m)
+	}
+	return prod
+}
+
+// retourne le ciphertext <ct> auquel on a ajouté la constante <c> dans chaque slot
+// ErrLevelMismatch si <ct> n'est pas un ciphertext de <ckks> (voir checkDefined)
+func (ckks *CKKS) CTAddConst(ct CT, c complex128) (CT, error) {
+	if err := ckks.checkDefined(ct); err != nil {
+		return CT{}, err
+	}
+	return ckks.addConst(ct, c), nil
+}
 
-	mod := new(big.Int) // nécessaire sinon les mod de ct1 et du prod pointent vers la meme adresse
-	mod.Set(ct1.Mod)
+// comme CTAddConst, pour un ciphertext valide
+func (ckks *CKKS) addConst(ct CT, c complex128) CT {
 
-	sum := NewCT(a, b, mod, ct1.Scale, ct1.L)
+	b := poly.AddRNS(ct.B, ckks.constToRNS(c, real(ct.Scale), ct.B.Moduli))
 
+	sum := NewCT(ct.A, b, ct.Mod, ct.Scale, ct.L)
+	sum.C = ct.C
 	return sum
 }
 
-// return the CT corresponding to the constant vector (k, ..., k) at the given scale
-func (ckks *CKKS) ConstToCT(k float64, mod *big.Int, scale complex128, pk [2]poly.Poly) CT {
-	enc := encoder.NewEncoder(ckks.N, scale)
+// retourne le ciphertext <ct> dont chaque slot a été multiplié par la constante <c>
+// la constante est encodée à l'échelle du dernier premier q_l de <ct> : après un RS, l'échelle est celle de <ct>
+// ErrLevelMismatch si <ct> n'est pas un ciphertext de <ckks> (voir checkDefined)
+func (ckks *CKKS) CTMultConst(ct CT, c complex128) (CT, error) {
+	if err := ckks.checkDefined(ct); err != nil {
+		return CT{}, err
+	}
+	return ckks.multConst(ct, c), nil
+}
+
+// comme CTMultConst, pour un ciphertext valide
+func (ckks *CKKS) multConst(ct CT, c complex128) CT {
+
+	qL := float64(ct.B.Moduli[ct.B.Level()])
+	m := ckks.constToRNS(c, qL, ct.B.Moduli)
+	a := poly.MultModRNS(ct.A, m)
+	b := poly.MultModRNS(ct.B, m)
+
+	prod := NewCT(a, b, ct.Mod, ct.Scale*complex(qL, 0), ct.L)
+	if ct.Degree() == 2 {
+		prod.C = poly.MultModRNS(ct.C, m)
+	}
+	return prod
+}
+
+// variante réelle de CTAddConst
+func (ckks *CKKS) CTAddFloat(ct CT, k float64) (CT, error) {
+	return ckks.CTAddConst(ct, complex(k, 0))
+}
+
+// variante réelle de CTMultConst
+func (ckks *CKKS) CTMultFloat(ct CT, k float64) (CT, error) {
+	return ckks.CTMultConst(ct, complex(k, 0))
+}
+
+// return the CT of level <L> corresponding to the constant vector (k, ..., k) at the given scale
+// ErrInvalidArgument si <L> n'est pas entre 0 et ckks.L
+func (ckks *CKKS) ConstToCT(k float64, L int, scale complex128, pk PublicKey) (CT, error) {
+	if L < 0 || L > ckks.L {
+		return CT{}, fmt.Errorf("%w : the level must be between 0 and %d, got %d", ErrInvalidArgument, ckks.L, L)
+	}
+	enc, err := encoder.NewEncoder(ckks.N, scale)
+	if err != nil {
+		return CT{}, err
+	}
 	pt := enc.ConstToPT(k)
 	ct := ckks.Encrypt(pt, pk)
-	res := NewCT(ct.A, ct.B, mod, scale, ckks.L)
-	return res
+	a := poly.DropLimbs(ct.A, L)
+	b := poly.DropLimbs(ct.B, L)
+	res := NewCT(a, b, a.Modulus(), scale, L)
+	return res, nil
 }
 
 //Increases the scale of the reciever by a factor k
 //This does not change the underlying message
-//AJOUTER ERREUR SI K < 0
-func (ct *CT) CTIncScale(k *big.Int) *CT {
+// ErrInvalidArgument si <k> n'est pas un entier strictement positif tenant sur un int64
+func (ct *CT) CTIncScale(k *big.Int) error {
+	if k.Sign() <= 0 || !k.IsInt64() {
+		return fmt.Errorf("%w : the scale factor must be a positive int64, got %v", ErrInvalidArgument, k)
+	}
+	ct.incScale(k)
+	return nil
+}
+
+// comme CTIncScale, pour un facteur <k> valide
+func (ct *CT) incScale(k *big.Int) {
 	ct.CTScale(k)
 	ct.Scale = ct.Scale * complex(float64(k.Int64()), 0)
-	return ct
 }
 
 // scales the reciever by a integer k. This does not work for non integer k.
 // this method changes the underlying message
 func (ct *CT) CTScale(k *big.Int) *CT {
 
-	ct.A = poly.Scale(ct.A, k)
-	ct.B = poly.Scale(ct.B, k)
+	ct.Seed = nil // A n'est plus celui tiré de la graine
+	ct.A = poly.ScaleRNS(ct.A, k)
+	ct.B = poly.ScaleRNS(ct.B, k)
+	if ct.Degree() == 2 {
+		ct.C = poly.ScaleRNS(ct.C, k)
+	}
 
 	return ct
 }
 
+// retourne le produit tensoriel (d0, d1, d2) de ct1 et ct2, ciphertext de degré 2 déchiffré en d0 + d1*s + d2*s^2
+// ct1 et ct2 doivent être de degré 1 (sinon ErrNotRelinearized) ; s'ils ne sont pas au même niveau, voir ckks.Alignment
+func (ckks *CKKS) CTMultNoRelin(ct1, ct2 CT) (CT, error) {
+
+	if err := ckks.checkDegree(ct1, ct2); err != nil {
+		return CT{}, err
+	}
+	ct1, ct2, err := ckks.multOperands(ct1, ct2)
+	if err != nil {
+		return CT{}, err
+	}
+	return ckks.tensor(ct1, ct2), nil
+}
+
+// comme CTMultNoRelin, pour des ciphertexts de degré 1 produits par les algorithmes de la bibliothèque :
+// celui de plus haut niveau est restreint au niveau de l'autre, quelle que soit ckks.Alignment
+func (ckks *CKKS) tensor(ct1, ct2 CT) CT {
+
+	if ct1.L > ct2.L {
+		ct1 = ckks.dropToLevel(ct1, ct2.L)
+	} else if ct2.L > ct1.L {
+		ct2 = ckks.dropToLevel(ct2, ct1.L)
+	}
+	d0 := poly.MultModRNS(ct1.B, ct2.B)
+	d1 := poly.AddRNS(poly.MultModRNS(ct1.A, ct2.B), poly.MultModRNS(ct1.B, ct2.A))
+	d2 := poly.MultModRNS(ct1.A, ct2.A)
+
+	prod := NewCT(d1, d0, d0.Modulus(), ct1.Scale*ct2.Scale, d0.Level())
+	prod.C = d2
+	return prod
+}
+
+// retourne le ciphertext de degré 1 chiffrant le même message que le ciphertext de degré 2 <ct>
+// la composante en s^2 est ramenée sous s à l'aide de l'evaluation key evk
+// un ciphertext de degré 1 est retourné tel quel
+func (ckks *CKKS) Relinearize(ct CT, evk SwitchingKey) (CT, error) {
+	if err := ckks.checkDefined(ct); err != nil {
+		return CT{}, err
+	}
+	if ct.Degree() == 2 && !ckks.isSwitchingKey(evk) {
+		return CT{}, fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
+	return ckks.relinearize(ct, evk), nil
+}
+
+// comme Relinearize, avec une evaluation key
+func (ckks *CKKS) relinearize(ct CT, evk SwitchingKey) CT {
+
+	if ct.Degree() == 1 {
+		return ct
+	}
+
+	res0, res1 := ckks.keySwitch(ct.C, evk)
+	res0 = poly.AddRNS(res0, ct.B)
+	res1 = poly.AddRNS(res1, ct.A)
+
+	res := NewCT(res1, res0, ct.Mod, ct.Scale, ct.L)
+	return res
+}
+
 // retourne le produit de ct1 et de ct1 en utilisant l'evaluation key evk
 //ON POURRAIT/DEVRAIT CHANGER LE TYPE DE RECIEVER.
-//ICI, LE RECIEVER CKKS EST JUSTE UTILISE POUR SON Q, MAIS C EST CELUI DES CT QUI COMPTE !
-//DEVRAIT CHECKER QUE LES DEUX CT ONT LE MEME MODULUS
-func (ckks *CKKS) CTMult(ct1, ct2 CT, evk [2]poly.Poly) CT {
+//ICI, LE RECIEVER CKKS EST JUSTE UTILISE POUR SES PREMIERS SPECIAUX, MAIS C EST LE NIVEAU DES CT QUI COMPTE !
+func (ckks *CKKS) CTMult(ct1, ct2 CT, evk SwitchingKey) (CT, error) {
+	if !ckks.isSwitchingKey(evk) {
+		return CT{}, fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
+	prod, err := ckks.CTMultNoRelin(ct1, ct2)
+	if err != nil {
+		return CT{}, err
+	}
+	return ckks.relinearize(prod, evk), nil
+}
 
-	d0 := poly.MultMod(ct1.B, ct2.B, ckks.Cyclo)
-	d1 := poly.Add(poly.MultMod(ct1.A, ct2.B, ckks.Cyclo), poly.MultMod(ct1.B, ct2.A, ckks.Cyclo))
-	d2 := poly.MultMod(ct1.A, ct2.A, ckks.Cyclo)
+// comme CTMult, pour des ciphertexts produits par les algorithmes de la bibliothèque (voir tensor)
+func (ckks *CKKS) mult(ct1, ct2 CT, evk SwitchingKey) CT {
+	return ckks.relinearize(ckks.tensor(ct1, ct2), evk)
+}
 
-	d0.TakeCoefMod(ct1.Mod)
-	d1.TakeCoefMod(ct1.Mod)
-	d2.TakeCoefMod(ct1.Mod)
+// retourne 5^k mod 2N, l'élément de Galois correspondant à une rotation de k slots vers la gauche
+func (ckks *CKKS) galoisElement(k int) int {
+	M := 2 * ckks.N
+	k = ((k % (ckks.N / 2)) + ckks.N/2) % (ckks.N / 2)
+	g := 1
+	for i := 0; i < k; i++ {
+		g = (5 * g) % M
+	}
+	return g
+}
 
-	res0 := poly.MultMod(d2, evk[0], ckks.Cyclo)
-	res0 = poly.ScaleDiv(res0, ckks.P)
-	res0 = poly.Add(res0, d0)
+// retourne la clé de rotation liée à la sk pour un décalage de k slots
+// c'est une clé de key switching de s(X^(5^k)) vers s(X)
+func (ckks *CKKS) RotationKeyGen(sk [2]poly.RNSPoly, k int) SwitchingKey {
 
-	res1 := poly.MultMod(d2, evk[1], ckks.Cyclo)
-	res1 = poly.ScaleDiv(res1, ckks.P)
-	res1 = poly.Add(res1, d1)
+	sRot := poly.Automorphism(sk[1], ckks.galoisElement(k))
+	rtk := ckks.switchingKeyGen(sRot, sk)
 
-	res0.TakeCoefMod(ct1.Mod)
-	res1.TakeCoefMod(ct1.Mod)
+	return rtk
+}
 
-	prod := NewCT(res1, res0, ct1.Mod, ct1.Scale*ct2.Scale, ct1.L)
-	return prod
+// retourne les clés de rotation liées à la sk pour les décalages 1, 2, 4, ..., N/4
+// elles suffisent à CTRotate pour effectuer n'importe quelle rotation
+func (ckks *CKKS) RotationKeysGen(sk [2]poly.RNSPoly) RotationKeys {
+	keys := make(RotationKeys)
+	for k := 1; k < ckks.N/2; k <<= 1 {
+		keys[k] = ckks.RotationKeyGen(sk, k)
+	}
+	return keys
+}
+
+// retourne l'image du ciphertext <ct> par l'automorphisme X -> X^<g>, ramenée sous la clé s
+// à l'aide de la clé de key switching <swk> de s(X^g) vers s(X)
+// <ct> doit être de degré 1 et <swk> une clé de <ckks> : les fonctions publiques le vérifient
+// (checkDegree, checkRotations) avant d'appeler applyAutomorphism
+func (ckks *CKKS) applyAutomorphism(ct CT, g int, swk SwitchingKey) CT {
+
+	a := poly.Automorphism(ct.A, g)
+	b := poly.Automorphism(ct.B, g)
+
+	res0, res1 := ckks.keySwitch(a, swk)
+	res0 = poly.AddRNS(res0, b)
+
+	res := NewCT(res1, res0, ct.Mod, ct.Scale, ct.L)
+	return res
+}
+
+// retourne un ciphertext dont le vecteur déchiffré est celui de <ct> décalé cycliquement de k slots vers la gauche :
+// le slot j contient le slot j+k de <ct>
+// si <keys> ne contient pas de clé pour k, la rotation est décomposée en rotations de puissances de 2
+// ErrMissingKey si l'une de ces clés manque (ou n'est pas une clé de <ckks>, voir isSwitchingKey),
+// ErrNotRelinearized si <ct> est de degré 2, ErrLevelMismatch s'il n'est pas un ciphertext de <ckks> (voir checkDefined)
+func (ckks *CKKS) CTRotate(ct CT, k int, keys RotationKeys) (CT, error) {
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	if err := ckks.checkRotations(keys, k); err != nil {
+		return CT{}, err
+	}
+	return ckks.rotate(ct, k, keys), nil
+}
+
+// retourne ErrMissingKey si <keys> ne permet pas d'effectuer les rotations de <ks> slots,
+// ni directement ni par décomposition en puissances de 2
+func (ckks *CKKS) checkRotations(keys RotationKeys, ks ...int) error {
+	slots := ckks.N / 2
+	for _, k := range ks {
+		k = ((k % slots) + slots) % slots
+		if k == 0 || ckks.isSwitchingKey(keys[k]) {
+			continue
+		}
+		for pow := 1; pow < slots; pow <<= 1 {
+			if k&pow != 0 && !ckks.isSwitchingKey(keys[pow]) {
+				return fmt.Errorf("%w : rotation by %d slots (needed for a rotation by %d)", ErrMissingKey, pow, k)
+			}
+		}
+	}
+	return nil
+}
+
+// retourne ErrLevelMismatch si l'un des ciphertexts <cts> n'est pas un ciphertext de <ckks> (voir checkDefined),
+// ErrNotRelinearized si l'un d'eux est de degré 2
+func (ckks *CKKS) checkDegree(cts ...CT) error {
+	if err := ckks.checkDefined(cts...); err != nil {
+		return err
+	}
+	for _, ct := range cts {
+		if ct.Degree() == 2 {
+			return ErrNotRelinearized
+		}
+	}
+	return nil
+}
+
+// retourne ErrLevelMismatch si l'un des ciphertexts <cts> n'est pas défini modulo q_0 * ... * q_l de <ckks>,
+// où l est son niveau : ciphertext d'une autre instance, ou dont le niveau ou le module ne correspondent pas à ses polynômes
+func (ckks *CKKS) checkDefined(cts ...CT) error {
+	for _, ct := range cts {
+		if ct.L < 0 || ct.L > ckks.L {
+			return fmt.Errorf("%w : level %d for a chain of %d levels", ErrLevelMismatch, ct.L, ckks.L)
+		}
+		moduli := ckks.Moduli[:ct.L+1]
+		if !ckks.definedOver(ct.A, moduli) || !ckks.definedOver(ct.B, moduli) || (ct.Degree() == 2 && !ckks.definedOver(ct.C, moduli)) {
+			return fmt.Errorf("%w : the ciphertext is not defined modulo q_0 * ... * q_%d", ErrLevelMismatch, ct.L)
+		}
+		if ct.Mod == nil || ct.Mod.Cmp(poly.ProdModuli(moduli)) != 0 {
+			return fmt.Errorf("%w : the modulus of the ciphertext is not q_0 * ... * q_%d", ErrLevelMismatch, ct.L)
+		}
+	}
+	return nil
+}
+
+// indique si <pol> est un polynôme de degré < N défini sur la base <moduli>
+func (ckks *CKKS) definedOver(pol poly.RNSPoly, moduli []uint64) bool {
+	if !equalModuli(pol.Moduli, moduli) || len(pol.Coefs) != len(moduli) {
+		return false
+	}
+	for _, limb := range pol.Coefs {
+		if len(limb) != ckks.N {
+			return false
+		}
+	}
+	return true
+}
+
+// indique si <swk> est une clé de key switching de <ckks> : une paire définie modulo Q * P par chiffre de Q
+// une clé vide (absente d'une RotationKeys, ou d'une autre instance) est traitée comme manquante (ErrMissingKey)
+func (ckks *CKKS) isSwitchingKey(swk SwitchingKey) bool {
+	if len(swk) != len(ckks.digits(ckks.L)) {
+		return false
+	}
+	moduli := ckks.modulusQP()
+	for _, digit := range swk {
+		if !ckks.definedOver(digit.B, moduli) || !ckks.definedOver(digit.A, moduli) {
+			return false
+		}
+	}
+	return true
+}
+
+// comme CTRotate, lorsque les clés nécessaires sont présentes (voir checkRotations)
+func (ckks *CKKS) rotate(ct CT, k int, keys RotationKeys) CT {
+
+	slots := ckks.N / 2
+	k = ((k % slots) + slots) % slots
+	if k == 0 {
+		return NewCT(ct.A, ct.B, ct.Mod, ct.Scale, ct.L)
+	}
+	if rtk := keys[k]; ckks.isSwitchingKey(rtk) {
+		return ckks.applyAutomorphism(ct, ckks.galoisElement(k), rtk)
+	}
+
+	res := ct
+	for pow := 1; pow < slots; pow <<= 1 {
+		if k&pow != 0 {
+			res = ckks.applyAutomorphism(res, ckks.galoisElement(pow), keys[pow])
+		}
+	}
+	return res
+}
+
+// retourne la clé de conjugaison liée à la sk
+// c'est une clé de key switching de s(X^-1) vers s(X)
+func (ckks *CKKS) ConjugationKeyGen(sk [2]poly.RNSPoly) SwitchingKey {
+
+	sConj := poly.Automorphism(sk[1], 2*ckks.N-1)
+	cjk := ckks.switchingKeyGen(sConj, sk)
+
+	return cjk
+}
+
+// retourne un ciphertext dont le vecteur déchiffré est le conjugué (slot par slot) de celui de <ct>
+// on utilise la clé de conjugaison <cjk>
+func (ckks *CKKS) CTConjugate(ct CT, cjk SwitchingKey) (CT, error) {
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	if !ckks.isSwitchingKey(cjk) {
+		return CT{}, fmt.Errorf("%w : conjugation key", ErrMissingKey)
+	}
+	return ckks.conjugate(ct, cjk), nil
+}
+
+// comme CTConjugate, pour un ciphertext de degré 1
+func (ckks *CKKS) conjugate(ct CT, cjk SwitchingKey) CT {
+	return ckks.applyAutomorphism(ct, 2*ckks.N-1, cjk)
+}
+
+// retourne un ciphertext dont le slot j contient la somme des slots j, j + batch, ..., j + (n-1)*batch de <ct>
+// (indices modulo N/2) : avec batch = 1 et n = N/2, chaque slot contient la somme de tous les slots
+// les sommes partielles de 2^i blocs sont obtenues par doublement, S_(i+1) = S_i + rot(S_i, 2^i * batch),
+// et celles correspondant aux bits de n sont accumulées : O(log(n)) rotations, aucun niveau n'est consommé
+func (ckks *CKKS) InnerSum(ct CT, batch, n int, keys RotationKeys) (CT, error) {
+
+	if batch < 1 || n < 1 || batch*n > ckks.N/2 {
+		return CT{}, fmt.Errorf("%w : InnerSum needs batch >= 1, n >= 1 and batch * n <= N/2", ErrInvalidArgument)
+	}
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	rotations := []int{}
+	for pow := 1; pow <= n; pow <<= 1 { // rotations effectuées par innerSum
+		if n&pow != 0 {
+			rotations = append(rotations, (n&(pow-1))*batch)
+		}
+		if pow<<1 <= n {
+			rotations = append(rotations, pow*batch)
+		}
+	}
+	if err := ckks.checkRotations(keys, rotations...); err != nil {
+		return CT{}, err
+	}
+	return ckks.innerSum(ct, batch, n, keys), nil
+}
+
+// comme InnerSum, pour des arguments valides
+func (ckks *CKKS) innerSum(ct CT, batch, n int, keys RotationKeys) CT {
+
+	var res CT
+	first := true
+	state := ct
+	offset := 0 // nombre de blocs déjà accumulés dans res
+	for pow := 1; pow <= n; pow <<= 1 {
+		if n&pow != 0 {
+			term := ckks.rotate(state, offset*batch, keys)
+			if first {
+				res, first = term, false
+			} else {
+				res = ckks.add(res, term)
+			}
+			offset += pow
+		}
+		if pow<<1 <= n {
+			state = ckks.add(state, ckks.rotate(state, pow*batch, keys))
+		}
+	}
+
+	return res
+}
+
+// retourne un ciphertext dont chaque slot contient le produit scalaire (sans conjugaison) sum_j a_j * b_j
+// des vecteurs chiffrés par <ct1> et <ct2>
+// le produit est relinéarisé puis divisé par le dernier premier (RS) : un niveau est consommé
+func (ckks *CKKS) InnerProduct(ct1, ct2 CT, evk SwitchingKey, keys RotationKeys) (CT, error) {
+
+	prod, err := ckks.CTMult(ct1, ct2, evk)
+	if err != nil {
+		return CT{}, err
+	}
+	if err := ckks.RS(&prod); err != nil {
+		return CT{}, err
+	}
+
+	return ckks.InnerSum(prod, 1, ckks.N/2, keys)
 }
 
 // returns a CT corresponding to the mean of the ciphertexts in the data list
-// computations are done under the pk and evk keys
-// ATTENTION : DEVRAIT CHECKER QUE LES DATA ONT LE MEME SCALING FACTOR
-func (ckks *CKKS) Mean(data []CT, pk, evk [2]poly.Poly) CT {
+// the division by n is a plaintext multiplication : no key is needed, and the result
+// has to be rescaled (RS) to get back the scale of the data
+// des data d'échelles ou de niveaux différents sont alignées par CTAdd (voir ckks.Alignment)
+func (ckks *CKKS) Mean(data []CT) (CT, error) {
 	N := ckks.N
 	n := len(data)
+	if n == 0 {
+		return CT{}, fmt.Errorf("%w : empty data list", ErrInvalidArgument)
+	}
 
-	mod := new(big.Int) // nécessaire sinon les mod de ct1 et du prod pointent vers la meme adresse
-	mod.Set(data[0].Mod)
-
-	res := NewCT(poly.ZeroPoly(ckks.N-1), poly.ZeroPoly(N-1), mod, data[0].Scale, data[0].L)
+	zero := poly.NewRNSPoly(N, data[0].A.Moduli)
+	zero.IsNTT = true
+	res := NewCT(zero, zero, data[0].Mod, data[0].Scale, data[0].L)
 	for i := 0; i < n; i++ {
-		res = ckks.CTAdd(res, data[i])
+		var err error
+		if res, err = ckks.CTAdd(res, data[i]); err != nil {
+			return CT{}, err
+		}
 	}
 
-	k := 1.0 / float64(n)
-	scale := complex(math.Log(float64(n))*100000000, 0.) // marche mieux que le scale basé sur data[0]
-	ctk := ckks.ConstToCT(k, mod, scale, pk)
-	res = ckks.CTMult(ctk, res, evk)
-	return res
+	return ckks.multConst(res, complex(1.0/float64(n), 0)), nil
 }
 
 // returns a CT corresponding to the var of the ciphertexts in the data list
-// paramètre delta pour l'appel de RS : à modifier : CKKS devrait contenir un champ delta
-func (ckks *CKKS) Var(data []CT, pk, evk [2]poly.Poly, delta *big.Int) CT {
-
-	mean := ckks.Mean(data, pk, evk)
-	// on ne RS pas ici sinon mean n'aura pas le même modulus que les data
-
-	scalesRatio := big.NewInt(int64(real(mean.Scale / data[0].Scale)))
+// the squares are not relinearized : their sum is relinearized once
+// les RS divisent par le dernier premier de la chaîne de modules : le résultat est à l'échelle du carré
+// de celle des data, deux niveaux sont consommés
+func (ckks *CKKS) Var(data []CT, evk SwitchingKey) (CT, error) {
+
+	mean, err := ckks.Mean(data)
+	if err != nil {
+		return CT{}, err
+	}
+	if err := ckks.RS(&mean); err != nil {
+		return CT{}, err
+	}
+	if !ckks.isSwitchingKey(evk) {
+		return CT{}, fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
 
-	//puts the (x - bar(x))^2 into the data variable
+	//puts the (x - bar(x))^2 into the data variable, as ciphertexts of degree 2
+	// les data sont ramenées au niveau de mean, sans changer leur échelle
 	for i := range data {
-		data[i].CTIncScale(scalesRatio)
+		if data[i], err = ckks.DropLevel(data[i], data[i].L-mean.L); err != nil {
+			return CT{}, err
+		}
 		data[i].CTScale(big.NewInt(-1))
-		data[i] = ckks.CTAdd(data[i], mean)
-		data[i] = ckks.CTMult(data[i], data[i], evk)
-		ckks.RS(&data[i], delta)
+		if data[i], err = ckks.CTAdd(data[i], mean); err != nil {
+			return CT{}, err
+		}
+		if data[i], err = ckks.CTMultNoRelin(data[i], data[i]); err != nil {
+			return CT{}, err
+		}
+	}
+
+	res, err := ckks.Mean(data)
+	if err != nil {
+		return CT{}, err
+	}
+	res = ckks.relinearize(res, evk)
+	if err := ckks.RS(&res); err != nil {
+		return CT{}, err
+	}
+	return res, nil
+}
+
+// returns a CT corresponding to the standard deviation of the ciphertexts in the data list
+// the variance must lie in <interval> = [a, b] with a > 0 (see CTSqrt), the result has the scale of the data
+// like Var, the ciphertexts of the data list are modified
+func (ckks *CKKS) StdDev(data []CT, evk SwitchingKey, interval [2]float64, iterations int) (CT, error) {
+
+	if len(data) == 0 {
+		return CT{}, fmt.Errorf("%w : empty data list", ErrInvalidArgument)
+	}
+	scale := real(data[0].Scale)
+	variance, err := ckks.Var(data, evk)
+	if err != nil {
+		return CT{}, err
+	}
+	// la variance est à l'échelle scale^2 : on la ramène à l'échelle des données
+	for real(variance.Scale)/float64(ckks.Moduli[variance.L]) > scale/2 {
+		if err := ckks.RS(&variance); err != nil {
+			return CT{}, err
+		}
 	}
 
-	res := ckks.Mean(data, pk, evk)
-	ckks.RS(&res, delta)
+	return ckks.CTSqrt(variance, interval, iterations, evk)
+}
+
+// retourne le ciphertext <ct> ramené <k> niveaux plus bas : les k derniers premiers de son module sont retirés
+// contrairement à RS, le message n'est pas divisé et l'échelle est conservée
+// ErrInvalidArgument si <k> < 0, ErrLevelExhausted si <k> dépasse le niveau de <ct>,
+// ErrLevelMismatch si <ct> n'est pas un ciphertext de <ckks> (voir checkDefined)
+func (ckks *CKKS) DropLevel(ct CT, k int) (CT, error) {
+	if k < 0 {
+		return CT{}, fmt.Errorf("%w : cannot drop %d levels", ErrInvalidArgument, k)
+	}
+	if err := ckks.checkDefined(ct); err != nil {
+		return CT{}, err
+	}
+	if k > ct.L {
+		return CT{}, fmt.Errorf("%w : cannot drop %d levels from level %d", ErrLevelExhausted, k, ct.L)
+	}
+	return ckks.dropToLevel(ct, ct.L-k), nil
+}
+
+// retourne le ciphertext <ct> restreint au niveau <level> (sans changer son échelle)
+// la graine de A est gardée : la restriction de A est tirée de la même graine (voir expandSeed)
+func (ckks *CKKS) dropToLevel(ct CT, level int) CT {
+	a := poly.DropLimbs(ct.A, level)
+	b := poly.DropLimbs(ct.B, level)
+	res := NewCT(a, b, a.Modulus(), ct.Scale, level)
+	res.Seed = ct.Seed
+	if ct.Degree() == 2 {
+		res.C = poly.DropLimbs(ct.C, level)
+	}
 	return res
 }
 
-// returns a rescaled version of ct by factor delta
+// returns a rescaled version of ct : divides it by the last prime q_L of its modulus chain
+// ErrLevelExhausted si <ct> est au niveau 0 (il n'est alors pas modifié), ErrLevelMismatch s'il n'est pas
+// un ciphertext de <ckks> (voir checkDefined)
 // DEVRAIT VERIFIER QUE LE SCALE DU CT EST ASSEZ GRAND POUR SUBIR LE RS
-func (ckks *CKKS) RS(ct *CT, delta *big.Int) *CT {
+func (ckks *CKKS) RS(ct *CT) error {
+	if err := ckks.checkDefined(*ct); err != nil {
+		return err
+	}
+	if ct.L == 0 {
+		return fmt.Errorf("%w : cannot rescale a ciphertext of level 0", ErrLevelExhausted)
+	}
+	ckks.rescale(ct)
+	return nil
+}
 
-	ct.A = poly.ScaleDiv(ct.A, delta)
-	ct.B = poly.ScaleDiv(ct.B, delta)
+// comme RS, pour un ciphertext de niveau >= 1
+func (ckks *CKKS) rescale(ct *CT) {
 
-	ct.Mod.Div(ct.Mod, delta)
+	qL := ct.A.Moduli[ct.A.Level()]
 
-	ct.A.TakeCoefMod(ct.Mod)
-	ct.B.TakeCoefMod(ct.Mod)
+	ct.Seed = nil // A n'est plus celui tiré de la graine
+	ct.A = poly.DivRoundByLastModuli(ct.A, 1)
+	ct.B = poly.DivRoundByLastModuli(ct.B, 1)
+	if ct.Degree() == 2 {
+		ct.C = poly.DivRoundByLastModuli(ct.C, 1)
+	}
+
+	ct.Mod = ct.A.Modulus()
 
-	temp := new(big.Float).SetInt(delta)
-	ttemp, _ := temp.Float64()
-	ct.Scale = ct.Scale / complex(ttemp, 0)
+	ct.Scale = ct.Scale / complex(float64(qL), 0)
 
 	ct.L = ct.L - 1
-	return ct
 }
@@ -0,0 +1,313 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Comparaisons homomorphes par approximation de la fonction signe (Cheon, Kim, Kim, "Efficient homomorphic
+// comparison methods with optimal complexity", 2020) : sign(x) ≈ f^(Df) ∘ g^(Dg)(x) sur [-1, 1],
+// où f_3 et g_3 sont des polynômes impairs de degré 7 ; g_3 envoie rapidement les petites valeurs vers ±1
+// et f_3 converge ensuite vers ±1. Chaque composition consomme 3 niveaux.
+
+// coefficients monomiaux de f_3 et g_3
+var (
+	signF = []float64{0, 35.0 / 16, 0, -35.0 / 16, 0, 21.0 / 16, 0, -5.0 / 16}
+	signG = []float64{0, 4589.0 / 1024, 0, -16577.0 / 1024, 0, 25614.0 / 1024, 0, -12860.0 / 1024}
+)
+
+// Nombre de compositions de g_3 et f_3 utilisées pour approcher la fonction signe
+type SignParameters struct {
+	Dg int
+	Df int
+}
+
+// retourne le nombre de niveaux consommés par CTSign
+func (params SignParameters) Depth() int {
+	return optimalDepth(len(signF)-1) * (params.Dg + params.Df)
+}
+
+// retourne f^(Df) ∘ g^(Dg)(<x>) (en clair)
+func (params SignParameters) eval(x float64) float64 {
+	g, f := NewMonomialPolynomial(signG), NewMonomialPolynomial(signF)
+	for i := 0; i < params.Dg; i++ {
+		x = g.Eval(x)
+	}
+	for i := 0; i < params.Df; i++ {
+		x = f.Eval(x)
+	}
+	return x
+}
+
+// retourne l'erreur max |sign(x) - f^(Df) ∘ g^(Dg)(x)| pour <eps> <= |x| <= 1
+// (estimée sur 4096 points, l'approximation étant impaire)
+func (params SignParameters) Error(eps float64) float64 {
+	err := 0.0
+	for i := 0; i <= 4096; i++ {
+		x := eps + (1-eps)*float64(i)/4096
+		err = math.Max(err, math.Abs(1-params.eval(x)))
+	}
+	return err
+}
+
+// retourne l'erreur max de CTMax et CTMin pour des messages dans [-1, 1] :
+// max(a, b) est calculé comme (a + b)/2 + x * sign(x) avec x = (a - b)/2, l'erreur est donc max_x |x| * |1 - F(x)|
+func (params SignParameters) MaxError() float64 {
+	err := 0.0
+	for i := 0; i <= 4096; i++ {
+		x := float64(i) / 4096
+		err = math.Max(err, x*math.Abs(1-params.eval(x)))
+	}
+	return err
+}
+
+// nombre maximal de compositions (Dg + Df) essayées par SignParametersFor, soit 3 * 16 = 48 niveaux
+const maxSignCompositions = 16
+
+// retourne les paramètres de profondeur minimale tels que l'erreur sur le signe soit au plus <err> pour |x| >= <eps>
+// ErrInvalidArgument si <eps> n'est pas dans ]0, 1[, si <err> n'est pas > 0, ou si aucune approximation
+// d'au plus maxSignCompositions compositions n'atteint cette précision
+func SignParametersFor(eps, err float64) (SignParameters, error) {
+	if !(eps > 0 && eps < 1) || !(err > 0) {
+		return SignParameters{}, fmt.Errorf("%w : eps must be in ]0, 1[ and err > 0, got %g and %g", ErrInvalidArgument, eps, err)
+	}
+	for total := 1; total <= maxSignCompositions; total++ {
+		for df := 1; df <= total; df++ {
+			params := SignParameters{Dg: total - df, Df: df}
+			if params.Error(eps) <= err {
+				return params, nil
+			}
+		}
+	}
+	return SignParameters{}, fmt.Errorf("%w : no sign approximation of at most %d compositions reaches an error of %g for |x| >= %g",
+		ErrInvalidArgument, maxSignCompositions, err, eps)
+}
+
+// retourne un ciphertext chiffrant une approximation de sign(x), où x est le message (réel) de <ct>, dans [-1, 1]
+// le résultat est à l'échelle de <ct>, params.Depth() niveaux sont consommés ; la précision est donnée par params.Error
+func (ckks *CKKS) CTSign(ct CT, params SignParameters, evk SwitchingKey) (CT, error) {
+	if err := ckks.checkSign(params, params.Depth(), evk, ct); err != nil {
+		return CT{}, err
+	}
+	return ckks.sign(ct, 1, 1, 0, params, evk), nil
+}
+
+// retourne une erreur si les ciphertexts <cts> ne peuvent pas être les arguments d'une comparaison de paramètres
+// <params> consommant <depth> niveaux : ErrInvalidArgument si on n'a pas Df >= 1 et Dg >= 0,
+// ErrNotRelinearized, ErrLevelExhausted ou ErrMissingKey
+func (ckks *CKKS) checkSign(params SignParameters, depth int, evk SwitchingKey, cts ...CT) error {
+	if params.Df < 1 || params.Dg < 0 {
+		return fmt.Errorf("%w : the sign approximation needs Df >= 1 and Dg >= 0", ErrInvalidArgument)
+	}
+	if err := ckks.checkDegree(cts...); err != nil {
+		return err
+	}
+	for _, ct := range cts {
+		if ct.L < depth {
+			return fmt.Errorf("%w : the comparison needs %d levels, a ciphertext has %d", ErrLevelExhausted, depth, ct.L)
+		}
+	}
+	if !ckks.isSwitchingKey(evk) {
+		return fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
+	return nil
+}
+
+// retourne un ciphertext chiffrant alpha * F(x / bound) + beta, où F = f^(Df) ∘ g^(Dg) et x est le message de <ct> :
+// la division par <bound> est faite sur les coefficients du premier polynôme, et la transformation affine
+// sur ceux du dernier, sans consommer de niveau
+func (ckks *CKKS) sign(ct CT, bound, alpha, beta float64, params SignParameters, evk SwitchingKey) CT {
+
+	polys := make([][]float64, 0, params.Dg+params.Df)
+	for i := 0; i < params.Dg; i++ {
+		polys = append(polys, append([]float64{}, signG...))
+	}
+	for i := 0; i < params.Df; i++ {
+		polys = append(polys, append([]float64{}, signF...))
+	}
+	for k := range polys[0] {
+		polys[0][k] /= math.Pow(bound, float64(k))
+	}
+	last := polys[len(polys)-1]
+	for k := range last {
+		last[k] *= alpha
+	}
+	last[0] += beta
+
+	res := ct
+	for _, coeffs := range polys {
+		res = ckks.evalPoly(res, NewMonomialPolynomial(coeffs), evk)
+	}
+	return res
+}
+
+// retourne un ciphertext chiffrant une approximation de 1 si a > b, 0 si a < b (1/2 si a = b),
+// où a et b, dans [-1, 1], sont les messages de <ct1> et <ct2> ; le résultat est à l'échelle de <ct1>
+// si |a - b| >= 2 eps, l'erreur est au plus params.Error(eps) / 2 ; params.Depth() niveaux sont consommés
+// si leurs niveaux ou leurs échelles diffèrent, <ct1> et <ct2> sont alignés selon ckks.Alignment (voir CTAdd)
+func (ckks *CKKS) CTCompare(ct1, ct2 CT, params SignParameters, evk SwitchingKey) (CT, error) {
+	ct1, ct2, err := ckks.addOperands(ct1, ct2)
+	if err != nil {
+		return CT{}, err
+	}
+	if err := ckks.checkSign(params, params.Depth(), evk, ct1); err != nil {
+		return CT{}, err
+	}
+	return ckks.compare(ct1, ct2, params, evk), nil
+}
+
+// comme CTCompare, pour des arguments valides
+func (ckks *CKKS) compare(ct1, ct2 CT, params SignParameters, evk SwitchingKey) CT {
+	return ckks.sign(ckks.ctSub(ct1, ct2), 2, 0.5, 0.5, params, evk)
+}
+
+// retourne un ciphertext chiffrant (slot par slot) le maximum des messages des ciphertexts de <cts>, dans [-1, 1]
+// les maxima sont calculés deux à deux en tournoi : MaxDepth(len(cts), params) niveaux sont consommés,
+// l'erreur de chaque tour est au plus params.MaxError()
+// si leurs niveaux ou leurs échelles diffèrent, les ciphertexts sont alignés selon ckks.Alignment (voir CTAdd)
+// ErrScaleMismatch ou ErrLevelMismatch si deux ciphertexts ne peuvent pas être alignés (voir Align)
+func (ckks *CKKS) CTMax(cts []CT, params SignParameters, evk SwitchingKey) (CT, error) {
+	return ckks.tournament(cts, 0.5, params, evk)
+}
+
+// retourne un ciphertext chiffrant (slot par slot) le minimum des messages des ciphertexts de <cts> (voir CTMax)
+func (ckks *CKKS) CTMin(cts []CT, params SignParameters, evk SwitchingKey) (CT, error) {
+	return ckks.tournament(cts, -0.5, params, evk)
+}
+
+// retourne le nombre de niveaux consommés par CTMax et CTMin sur <n> ciphertexts
+func MaxDepth(n int, params SignParameters) int {
+	return powerDepth(n) * (params.Depth() + 1)
+}
+
+// réduit <cts> deux à deux par maxOrMin jusqu'à un seul ciphertext
+func (ckks *CKKS) tournament(cts []CT, alpha float64, params SignParameters, evk SwitchingKey) (CT, error) {
+
+	if len(cts) == 0 {
+		return CT{}, fmt.Errorf("%w : empty list of ciphertexts", ErrInvalidArgument)
+	}
+	if err := ckks.checkSign(params, MaxDepth(len(cts), params), evk, cts...); err != nil {
+		return CT{}, err
+	}
+	if ckks.Alignment == AlignStrict {
+		// un ciphertext resté seul n'est comparé qu'à des résultats intermédiaires
+		for _, ct := range cts[1:] {
+			if err := ckks.CheckOperands(cts[0], ct); err != nil {
+				return CT{}, err
+			}
+		}
+	}
+
+	round := cts
+	for len(round) > 1 {
+		next := make([]CT, 0, (len(round)+1)/2)
+		for i := 0; i+1 < len(round); i += 2 {
+			ct, err := ckks.maxOrMin(round[i], round[i+1], alpha, params, evk)
+			if err != nil {
+				return CT{}, err
+			}
+			next = append(next, ct)
+		}
+		if len(round)%2 == 1 {
+			// un ciphertext resté seul a un niveau de plus que les résultats de ce tour : il est aligné quelle que soit la politique
+			_, last, err := ckks.Align(next[0], round[len(round)-1])
+			if err != nil {
+				return CT{}, err
+			}
+			next = append(next, last)
+		}
+		round = next
+	}
+	return round[0], nil
+}
+
+// retourne (a + b)/2 + x * 2*alpha * sign(x) avec x = (a - b)/2 : max(a, b) pour alpha = 1/2, min(a, b) pour alpha = -1/2
+// le résultat est à l'échelle de <ct1>, params.Depth() + 1 niveaux sont consommés
+// erreurs de addOperands si <ct1> et <ct2> ne peuvent pas être alignés selon ckks.Alignment
+func (ckks *CKKS) maxOrMin(ct1, ct2 CT, alpha float64, params SignParameters, evk SwitchingKey) (CT, error) {
+
+	ct1, ct2, err := ckks.addOperands(ct1, ct2)
+	if err != nil {
+		return CT{}, err
+	}
+	scale := real(ct1.Scale)
+	diff := ckks.ctSub(ct1, ct2)
+	s := ckks.sign(diff, 2, alpha, 0, params, evk)
+
+	d := ckks.multConstTo(diff, 1, s.L, float64(ckks.Moduli[s.L]))
+	prod := ckks.mult(d, s, evk)
+	ckks.rescale(&prod)
+	prod.Scale = complex(scale, 0)
+
+	mean := ckks.multConstTo(ckks.add(ct1, ct2), 0.5, prod.L, scale)
+	return ckks.add(prod, mean), nil
+}
+
+// retourne, pour chaque ciphertext de <cts>, un ciphertext chiffrant (slot par slot) une approximation de 1
+// s'il contient le maximum et de 0 sinon (des messages égaux se partagent l'indicateur de manière dégradée)
+// l'indicateur de i est le produit des CTCompare(cts[i], cts[j]) pour j != i : ArgmaxDepth(len(cts), params) niveaux sont consommés
+// si leurs niveaux ou leurs échelles diffèrent, les ciphertexts sont alignés deux à deux selon ckks.Alignment (voir CTAdd)
+func (ckks *CKKS) CTArgmax(cts []CT, params SignParameters, evk SwitchingKey) ([]CT, error) {
+
+	n := len(cts)
+	if n < 2 {
+		return nil, fmt.Errorf("%w : at least two ciphertexts are needed", ErrInvalidArgument)
+	}
+	if err := ckks.checkSign(params, ArgmaxDepth(n, params), evk, cts...); err != nil {
+		return nil, err
+	}
+
+	cmp := make([][]CT, n)
+	for i := range cmp {
+		cmp[i] = make([]CT, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			ct1, ct2, err := ckks.addOperands(cts[i], cts[j])
+			if err != nil {
+				return nil, err
+			}
+			cmp[i][j] = ckks.compare(ct1, ct2, params, evk)
+			// 1 - c ne consomme pas de niveau
+			neg := NewCT(cmp[i][j].A, cmp[i][j].B, cmp[i][j].Mod, cmp[i][j].Scale, cmp[i][j].L)
+			neg.CTScale(big.NewInt(-1))
+			cmp[j][i] = ckks.addConst(neg, 1)
+		}
+	}
+
+	res := make([]CT, n)
+	for i := 0; i < n; i++ {
+		factors := make([]CT, 0, n-1)
+		for j := 0; j < n; j++ {
+			if j != i {
+				factors = append(factors, cmp[i][j])
+			}
+		}
+		res[i] = ckks.productTree(factors, evk)
+	}
+	return res, nil
+}
+
+// retourne le nombre de niveaux consommés par CTArgmax sur <n> ciphertexts
+func ArgmaxDepth(n int, params SignParameters) int {
+	return params.Depth() + powerDepth(n-1)
+}
+
+// retourne le produit des ciphertexts de <cts>, de même niveau, calculé en arbre : ceil(log2(len(cts))) niveaux sont consommés
+func (ckks *CKKS) productTree(cts []CT, evk SwitchingKey) CT {
+	for len(cts) > 1 {
+		next := make([]CT, 0, (len(cts)+1)/2)
+		for i := 0; i+1 < len(cts); i += 2 {
+			prod := ckks.mult(cts[i], cts[i+1], evk)
+			ckks.rescale(&prod)
+			next = append(next, prod)
+		}
+		if len(cts)%2 == 1 {
+			last := cts[len(cts)-1]
+			next = append(next, ckks.multConstTo(last, 1, last.L-1, real(next[0].Scale)))
+		}
+		cts = next
+	}
+	return cts[0]
+}
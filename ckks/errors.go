@@ -0,0 +1,26 @@
+package ckks
+
+import (
+	"errors"
+
+	"kazat.ch/lbcrypto/encoder"
+	"kazat.ch/lbcrypto/poly"
+)
+
+// Erreurs retournées par les fonctions du package (éventuellement enveloppées avec fmt.Errorf et %w,
+// elles se testent avec errors.Is)
+// Les constructeurs (NewCKKS, NewChebyshevPolynomial, ...) retournent ErrInvalidArgument sur des paramètres invalides ;
+// seule la violation d'un invariant interne (une erreur de programmation de la bibliothèque) provoque une panique
+var (
+	ErrLevelExhausted  = errors.New("not enough levels")
+	ErrScaleMismatch   = errors.New("scale mismatch")
+	ErrLevelMismatch   = errors.New("level mismatch")
+	ErrDimension       = encoder.ErrDimension // la même valeur que encoder.ErrDimension et cMat.ErrDimension
+	ErrMissingKey      = errors.New("missing key")
+	ErrNotRelinearized = errors.New("ciphertext of degree 2 must be relinearized")
+	ErrInvalidArgument = poly.ErrInvalidArgument // la même valeur que poly.ErrInvalidArgument et encoder.ErrInvalidArgument
+
+	ErrInsecureParameters = errors.New("parameters below the requested security level")
+	ErrFormat             = poly.ErrFormat // la même valeur que poly.ErrFormat et encoder.ErrFormat
+	ErrParameterMismatch  = errors.New("object serialized under different parameters")
+)
@@ -0,0 +1,193 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+
+	"kazat.ch/lbcrypto/poly"
+	"kazat.ch/lbcrypto/random"
+)
+
+// Clé de key switching hybride (Han et Ki) : une paire (-a_j*s + e_j + P*g_j*s', a_j) définie modulo Q * P
+// par chiffre Q_j de la décomposition de Q, où g_j vaut 1 modulo Q_j et 0 modulo les autres chiffres
+type SwitchingKey []SwitchingKeyDigit
+
+// Paire (B, A) = (-a_j*s + e_j + P*g_j*s', a_j) d'un chiffre d'une clé de key switching
+type SwitchingKeyDigit struct {
+	B    poly.RNSPoly
+	A    poly.RNSPoly
+	Seed []byte // graine dont A est tiré (voir uniformNTT), nil si A n'est pas tiré d'une graine
+}
+
+// Décrit le coût et le bruit du key switching d'une instance du schéma
+type KeySwitchingInfo struct {
+	Dnum     int     // nombre de chiffres de la décomposition de Q
+	LogQ     int     // taille en bits de Q
+	LogP     int     // taille en bits de P
+	LogQP    int     // taille en bits du module Q * P des clés (détermine la sécurité à N fixé)
+	KeySize  int     // taille en octets d'une clé de key switching sérialisée (evk, clé de rotation ou de conjugaison)
+	NoiseStd float64 // écart-type estimé de l'erreur ajoutée (sur chaque coefficient) par un key switching au niveau L
+}
+
+// retourne les intervalles [début, fin[ des indices des premiers de q_0, ..., q_level formant chaque chiffre
+// les chiffres sont formés de ceil((L+1)/dnum) premiers consécutifs de la chaîne
+func (ckks *CKKS) digits(level int) [][2]int {
+	alpha := (ckks.L + ckks.Dnum) / ckks.Dnum
+	res := [][2]int{}
+	for start := 0; start <= level; start += alpha {
+		end := start + alpha
+		if end > level+1 {
+			end = level + 1
+		}
+		res = append(res, [2]int{start, end})
+	}
+	return res
+}
+
+// retourne la restriction de la clé <key>, définie modulo Q * P, au module q_0 * ... * q_level * P
+func (ckks *CKKS) restrictKey(key poly.RNSPoly, level int) poly.RNSPoly {
+	nbQ := len(ckks.Moduli)
+	qPart := poly.RNSPoly{Coefs: key.Coefs[:level+1], Moduli: key.Moduli[:level+1], IsNTT: key.IsNTT}
+	pPart := poly.RNSPoly{Coefs: key.Coefs[nbQ:], Moduli: key.Moduli[nbQ:], IsNTT: key.IsNTT}
+	return poly.ConcatRNS(qPart, pPart)
+}
+
+// retourne une clé de key switching hybride définie modulo Q * P, permettant de passer
+// d'un ciphertext déchiffrable sous <sIn> à un ciphertext déchiffrable sous la sk <sk> = (1, s)
+// chaque a_j est tiré de sa propre graine (voir uniformNTT), seule écrite par MarshalBinary
+func (ckks *CKKS) switchingKeyGen(sIn poly.RNSPoly, sk [2]poly.RNSPoly) SwitchingKey {
+
+	as, seeds := []poly.RNSPoly{}, [][]byte{}
+	for range ckks.digits(ckks.L) {
+		a, seed := ckks.uniformNTT(ckks.modulusQP())
+		as, seeds = append(as, a), append(seeds, seed)
+	}
+	return ckks.switchingKeyGenWith(sIn, sk[1], as, seeds)
+}
+
+// comme switchingKeyGen, vers le secret <s> et avec les polynômes uniformes <as> (un par chiffre) définis modulo Q * P,
+// tirés des graines <seeds>
+func (ckks *CKKS) switchingKeyGenWith(sIn, s poly.RNSPoly, as []poly.RNSPoly, seeds [][]byte) SwitchingKey {
+
+	moduli := ckks.modulusQP()
+	pS := poly.ScaleRNS(sIn, ckks.P) // nul modulo les premiers spéciaux
+
+	swk := SwitchingKey{}
+	for j, digit := range ckks.digits(ckks.L) {
+		a := as[j]
+		e := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), moduli)
+
+		b := poly.NegRNS(poly.MultModRNS(a, s))
+		b = poly.AddRNS(b, e)
+
+		// P*g_j*s' vaut P*s' modulo les premiers du chiffre et 0 ailleurs
+		gS := poly.NewRNSPoly(ckks.N, moduli)
+		gS.IsNTT = true
+		for i := digit[0]; i < digit[1]; i++ {
+			copy(gS.Coefs[i], pS.Coefs[i])
+		}
+		b = poly.AddRNS(b, gS)
+
+		swk = append(swk, SwitchingKeyDigit{B: b, A: a, Seed: seeds[j]})
+	}
+
+	return swk
+}
+
+// retourne une clé de key switching de la sk <skOld> vers la sk <skNew>
+// elle est construite comme l'evaluation key (même clé scalée par P), avec s_old à la place de s^2
+func (ckks *CKKS) SwitchingKeyGen(skOld, skNew [2]poly.RNSPoly) SwitchingKey {
+
+	swk := ckks.switchingKeyGen(skOld[1], skNew)
+
+	return swk
+}
+
+// retourne un ciphertext chiffrant le même message que <ct> sous la sk de destination de la clé <swk>
+// <ct> doit être déchiffrable sous la sk de départ de <swk> et de degré 1 (sinon ErrNotRelinearized) ;
+// il n'est jamais déchiffré
+func (ckks *CKKS) KeySwitch(ct CT, swk SwitchingKey) (CT, error) {
+
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	if !ckks.isSwitchingKey(swk) {
+		return CT{}, fmt.Errorf("%w : switching key", ErrMissingKey)
+	}
+
+	res0, res1 := ckks.keySwitch(ct.A, swk)
+	res0 = poly.AddRNS(res0, ct.B)
+
+	res := NewCT(res1, res0, ct.Mod, ct.Scale, ct.L)
+	return res, nil
+}
+
+// retourne (c0, c1) tel que c0 + c1*s ≈ <d> * s' où <key> est une clé de key switching de s' vers s
+// d est découpé selon les chiffres Q_j, chaque morceau est remonté modulo Q_l * P et multiplié
+// par la paire correspondante de la clé, puis la somme est redescendue par division par P
+// <d> et <key> sont en forme NTT, ainsi que le résultat
+func (ckks *CKKS) keySwitch(d poly.RNSPoly, key SwitchingKey) (poly.RNSPoly, poly.RNSPoly) {
+
+	level := d.Level()
+	nbP := len(ckks.SpecialModuli)
+	dCoef := poly.INTT(d)
+	moduliQP := ckks.restrictKey(key[0].B, level).Moduli
+
+	var c0, c1 poly.RNSPoly
+	for j, digit := range ckks.digits(level) {
+		dj := poly.RNSPoly{Coefs: dCoef.Coefs[digit[0]:digit[1]], Moduli: dCoef.Moduli[digit[0]:digit[1]]}
+		// exact modulo les premiers du chiffre, à un multiple de Q_j près ailleurs
+		djQP := poly.NTT(poly.ConvertBasis(dj, moduliQP))
+
+		prod0 := poly.MultModRNS(djQP, ckks.restrictKey(key[j].B, level))
+		prod1 := poly.MultModRNS(djQP, ckks.restrictKey(key[j].A, level))
+		if j == 0 {
+			c0, c1 = prod0, prod1
+		} else {
+			c0 = poly.AddRNS(c0, prod0)
+			c1 = poly.AddRNS(c1, prod1)
+		}
+	}
+
+	return poly.DivRoundByLastModuli(c0, nbP), poly.DivRoundByLastModuli(c1, nbP)
+}
+
+// retourne la taille des modules, la taille des clés et le bruit estimé du key switching
+// l'erreur ajoutée est sum_j d_j*e_j / P + erreur d'arrondi, où d_j est de l'ordre de Q_j :
+// son écart-type est estimé par sqrt(dnum * N * s2 * (Q_j/P)^2 / 12 + (1 + H) / 12)
+func (ckks *CKKS) KeySwitchingInfo() KeySwitchingInfo {
+
+	maxDigit := 0.0
+	for _, digit := range ckks.digits(ckks.L) {
+		logQj := bitLen(poly.ProdModuli(ckks.Moduli[digit[0]:digit[1]]))
+		maxDigit = math.Max(maxDigit, logQj)
+	}
+	logP := bitLen(ckks.P)
+	ratio := math.Pow(2, maxDigit-logP)
+
+	dnum := len(ckks.digits(ckks.L))
+	variance := float64(dnum*ckks.N)*ckks.s2*ratio*ratio/12 + float64(1+ckks.H)/12
+
+	// b_j est écrit avec des coefficients de la taille des premiers, a_j est remplacé par sa graine
+	polySize := 0
+	for _, q := range ckks.modulusQP() {
+		polySize += ckks.N * ((bits.Len64(q) + 7) / 8)
+	}
+	info := KeySwitchingInfo{
+		Dnum:     dnum,
+		LogQ:     ckks.Q.BitLen(),
+		LogP:     ckks.P.BitLen(),
+		LogQP:    new(big.Int).Mul(ckks.Q, ckks.P).BitLen(),
+		KeySize:  dnum * (polySize + random.SeedSize),
+		NoiseStd: math.Sqrt(variance),
+	}
+	return info
+}
+
+// retourne log2(<x>) pour un grand entier positif
+func bitLen(x *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(x).Float64()
+	return math.Log2(f)
+}
@@ -0,0 +1,373 @@
+package ckks
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+
+	"kazat.ch/lbcrypto/cMat"
+	"kazat.ch/lbcrypto/encoder"
+	"kazat.ch/lbcrypto/poly"
+)
+
+// Produit d'un ciphertext par une matrice en clair (Halevi et Shoup) : en notant diag_k[j] = M[j][(j+k) mod n]
+// la k-ème diagonale de M, M * z = sum_k diag_k * rot(z, k)
+// avec baby-step giant-step (k = g*n1 + b, 0 <= b < n1), comme rot(diag, g*n1) * rot(z, g*n1 + b) = rot(diag * rot(z, b), g*n1) :
+// M * z = sum_g rot(sum_b rot(diag_(g*n1+b), -g*n1) * rot(z, b), g*n1)
+// seules les rotations de z par les baby steps b et celles des sommes internes par les giant steps g*n1 sont
+// nécessaires, soit environ 2*sqrt(n) rotations au lieu de n
+// une matrice r x c (r, c <= N/2) est complétée par des zéros en une matrice N/2 x N/2 : seuls les c premiers slots
+// de z sont utilisés, les r premiers slots du résultat contiennent M * z et les autres 0
+
+// Matrice en clair dont les diagonales sont pré-encodées (et déjà tournées des giant steps), voir EvalEncodedMatrix
+type EncodedMatrix struct {
+	Rows   int
+	Cols   int
+	Level  int     // niveau des ciphertexts auxquels elle s'applique (un ciphertext de niveau supérieur est restreint)
+	Levels int     // nombre de niveaux consommés par le produit
+	Scale  float64 // échelle d'encodage des diagonales
+	Baby   int     // nombre n1 de baby steps
+	diags  map[int]map[int]poly.RNSPoly
+}
+
+// entrée du cache des matrices encodées par LinearTransform (voir matrixCache)
+type cachedMatrix struct {
+	key  uint64
+	data []complex128
+	em   EncodedMatrix
+}
+
+// retourne la matrice <M> encodée pour des ciphertexts de niveau <level>
+// les diagonales sont encodées à l'échelle q_level : le produit consomme un niveau et conserve l'échelle du ciphertext
+// ErrDimension si la matrice a plus de N/2 lignes ou colonnes, ErrInvalidArgument si <level> n'est pas entre 1 et L
+func (ckks *CKKS) EncodeMatrix(M cMat.CMat, level int) (EncodedMatrix, error) {
+
+	rows, cols := M.Dims()
+	if rows > ckks.N/2 || cols > ckks.N/2 {
+		return EncodedMatrix{}, fmt.Errorf("%w : a %d x %d matrix does not fit in %d slots", ErrDimension, rows, cols, ckks.N/2)
+	}
+	if level < 1 || level > ckks.L {
+		return EncodedMatrix{}, fmt.Errorf("%w : the level must be between 1 and %d, got %d", ErrInvalidArgument, ckks.L, level)
+	}
+
+	data := M.GetData()
+	Mrows := make([][]complex128, rows)
+	for j := range Mrows {
+		Mrows[j] = data[j*cols : (j+1)*cols]
+	}
+
+	return ckks.encodeDiagonals(diagonals(Mrows, ckks.N/2), rows, cols, level, 1, float64(ckks.Moduli[level])), nil
+}
+
+// retourne la matrice de diagonales <diags> (de taille <rows> x <cols>) encodée à l'échelle <scale>
+// pour des ciphertexts de niveau <level>, le produit consommant <levels> niveaux
+// le nombre de baby steps n1 (une puissance de 2) minimise le nombre de rotations
+func (ckks *CKKS) encodeDiagonals(diags map[int][]complex128, rows, cols, level, levels int, scale float64) EncodedMatrix {
+
+	n := ckks.N / 2
+	if len(diags) == 0 { // matrice nulle : une diagonale nulle suffit
+		diags = map[int][]complex128{0: make([]complex128, n)}
+	}
+	baby := babySteps(diagonalIndices(diags), n)
+
+	enc, err := encoder.NewEncoder(ckks.N, complex(scale, 0))
+	if err != nil { // l'échelle est celle, positive, d'un ciphertext
+		panic("Error : " + err.Error())
+	}
+	moduli := ckks.Moduli[:level+1]
+	encoded := make(map[int]map[int]poly.RNSPoly)
+	for k, diag := range diags {
+		g, b := k/baby, k%baby
+		if encoded[g] == nil {
+			encoded[g] = make(map[int]poly.RNSPoly)
+		}
+		rotated := make([]complex128, n)
+		for j := range rotated {
+			rotated[j] = diag[((j-g*baby)%n+n)%n]
+		}
+		v := cMat.NewCMat(n, 1, rotated)
+		pt, _ := enc.Encode(&v) // v a toujours N/2 composantes
+		encoded[g][b] = ckks.ptToRNS(pt, moduli)
+	}
+
+	em := EncodedMatrix{
+		Rows:   rows,
+		Cols:   cols,
+		Level:  level,
+		Levels: levels,
+		Scale:  scale,
+		Baby:   baby,
+		diags:  encoded,
+	}
+	return em
+}
+
+// retourne le nombre de baby steps n1 (une puissance de 2 au plus <n>) minimisant le nombre de rotations
+// du produit par les diagonales d'indices <ks>
+func babySteps(ks []int, n int) int {
+	baby, best := 1, math.MaxInt32
+	for n1 := 1; n1 <= n; n1 <<= 1 {
+		babies, giants := map[int]bool{}, map[int]bool{}
+		for _, k := range ks {
+			babies[k%n1] = true
+			giants[k/n1] = true
+		}
+		if cost := len(babies) + len(giants); cost < best {
+			baby, best = n1, cost
+		}
+	}
+	return baby
+}
+
+// retourne les décalages (par ordre croissant) des rotations du produit par les diagonales d'indices <ks>
+// avec <baby> baby steps : les baby steps b et les giant steps g*<baby> non nuls, pour k = g*<baby> + b
+func bsgsRotations(ks []int, baby int) []int {
+	set := map[int]bool{}
+	for _, k := range ks {
+		if b := k % baby; b != 0 {
+			set[b] = true
+		}
+		if g := k / baby; g != 0 {
+			set[g*baby] = true
+		}
+	}
+	res := make([]int, 0, len(set))
+	for k := range set {
+		res = append(res, k)
+	}
+	sort.Ints(res)
+	return res
+}
+
+// retourne les indices des diagonales <diags>
+func diagonalIndices(diags map[int][]complex128) []int {
+	ks := make([]int, 0, len(diags))
+	for k := range diags {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+// retourne les décalages des rotations effectuées par EvalEncodedMatrix : les clés de rotation correspondantes
+// évitent la décomposition des rotations en puissances de 2 par CTRotate
+func (em EncodedMatrix) Rotations() []int {
+	ks := []int{}
+	for g, diags := range em.diags {
+		for b := range diags {
+			ks = append(ks, g*em.Baby+b)
+		}
+	}
+	return bsgsRotations(ks, em.Baby)
+}
+
+// retourne le ciphertext dont les slots sont M * z, où z sont les slots de <ct> et M la matrice encodée <em>
+// le résultat est au niveau em.Level - em.Levels, à l'échelle ct.Scale * em.Scale divisée par les em.Levels
+// derniers premiers q_em.Level, ... de la chaîne
+// ErrLevelExhausted si <ct> est sous le niveau em.Level, ErrMissingKey si <keys> ne permet pas toutes les rotations
+func (ckks *CKKS) EvalEncodedMatrix(ct CT, em EncodedMatrix, keys RotationKeys) (CT, error) {
+
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	if ct.L < em.Level {
+		return CT{}, fmt.Errorf("%w : the matrix is encoded for level %d, the ciphertext has level %d", ErrLevelExhausted, em.Level, ct.L)
+	}
+	if err := ckks.checkRotations(keys, em.Rotations()...); err != nil {
+		return CT{}, err
+	}
+	return ckks.evalEncodedMatrix(ct, em, keys), nil
+}
+
+// comme EvalEncodedMatrix, pour des arguments valides
+func (ckks *CKKS) evalEncodedMatrix(ct CT, em EncodedMatrix, keys RotationKeys) CT {
+
+	ct = ckks.dropToLevel(ct, em.Level)
+	scale := ct.Scale * complex(em.Scale, 0)
+
+	babies := make(map[int]CT)
+	var res CT
+	first := true
+	for g, diags := range em.diags {
+		var inner CT
+		innerFirst := true
+		for b, m := range diags {
+			if _, ok := babies[b]; !ok {
+				babies[b] = ckks.rotate(ct, b, keys)
+			}
+			rot := babies[b]
+			term := NewCT(poly.MultModRNS(rot.A, m), poly.MultModRNS(rot.B, m), ct.Mod, scale, ct.L)
+			if innerFirst {
+				inner, innerFirst = term, false
+			} else {
+				inner = ckks.add(inner, term)
+			}
+		}
+		inner = ckks.rotate(inner, g*em.Baby, keys)
+		if first {
+			res, first = inner, false
+		} else {
+			res = ckks.add(res, inner)
+		}
+	}
+	for i := 0; i < em.Levels; i++ {
+		ckks.rescale(&res)
+	}
+	return res
+}
+
+// retourne le ciphertext dont les slots sont M * z, où z sont les slots de <ct> (seuls les c premiers sont utilisés
+// si M est de taille r x c) ; les r premiers slots du résultat contiennent M * z et les autres 0
+// un niveau est consommé et l'échelle de <ct> est conservée
+// les diagonales encodées des dernières matrices sont gardées en cache (voir matrixCache) : appliquer la même matrice
+// au même niveau ne les réencode pas ; LinearTransform peut être appelée depuis plusieurs goroutines
+// ErrLevelExhausted si <ct> est au niveau 0 (voir aussi EncodeMatrix et EvalEncodedMatrix)
+func (ckks *CKKS) LinearTransform(ct CT, M cMat.CMat, keys RotationKeys) (CT, error) {
+	if ct.L < 1 {
+		return CT{}, fmt.Errorf("%w : a linear transform needs one level", ErrLevelExhausted)
+	}
+	em, err := ckks.cachedEncodeMatrix(M, ct.L)
+	if err != nil {
+		return CT{}, err
+	}
+	return ckks.EvalEncodedMatrix(ct, em, keys)
+}
+
+// retourne EncodeMatrix(<M>, <level>), en la cherchant d'abord dans le cache
+// les matrices sont indexées par un haché de leurs dimensions, du niveau et des coefficients,
+// et les coefficients sont comparés pour écarter les collisions
+// sans cache (une instance qui n'a pas été créée par NewCKKS), la matrice est simplement encodée
+func (ckks *CKKS) cachedEncodeMatrix(M cMat.CMat, level int) (EncodedMatrix, error) {
+
+	rows, cols := M.Dims()
+	data := M.GetData()
+
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	write := func(x uint64) {
+		for i := range buf {
+			buf[i] = byte(x >> (8 * i))
+		}
+		h.Write(buf)
+	}
+	write(uint64(rows))
+	write(uint64(cols))
+	write(uint64(level))
+	for _, c := range data {
+		write(math.Float64bits(real(c)))
+		write(math.Float64bits(imag(c)))
+	}
+	key := h.Sum64()
+
+	if em, ok := ckks.matrices.get(key, data, rows, cols, level); ok {
+		return em, nil
+	}
+	em, err := ckks.EncodeMatrix(M, level)
+	if err != nil {
+		return EncodedMatrix{}, err
+	}
+	ckks.matrices.put(key, data, em)
+	return em, nil
+}
+
+// nombre maximal de matrices encodées gardées par le cache de LinearTransform
+const matrixCacheSize = 16
+
+// Cache LRU des matrices encodées par LinearTransform : au plus matrixCacheSize matrices sont gardées,
+// la moins récemment utilisée étant retirée la première ; le verrou permet d'utiliser une instance
+// depuis plusieurs goroutines
+type matrixCache struct {
+	mu      sync.Mutex
+	entries map[uint64]*list.Element // éléments de order, de valeur *cachedMatrix
+	order   *list.List               // de la plus récemment à la moins récemment utilisée
+}
+
+func newMatrixCache() *matrixCache {
+	return &matrixCache{entries: make(map[uint64]*list.Element), order: list.New()}
+}
+
+// retourne la matrice de clé <key> encodée pour des ciphertexts de niveau <level>, si elle est en cache
+// et a les coefficients <data> et les dimensions <rows> x <cols>
+func (cache *matrixCache) get(key uint64, data []complex128, rows, cols, level int) (EncodedMatrix, bool) {
+	if cache == nil {
+		return EncodedMatrix{}, false
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	elem, ok := cache.entries[key]
+	if !ok {
+		return EncodedMatrix{}, false
+	}
+	entry := elem.Value.(*cachedMatrix)
+	if entry.em.Rows != rows || entry.em.Cols != cols || entry.em.Level != level || !equalData(entry.data, data) {
+		return EncodedMatrix{}, false
+	}
+	cache.order.MoveToFront(elem)
+	return entry.em, true
+}
+
+// ajoute au cache la matrice <em> de clé <key> et de coefficients <data>, en retirant si besoin
+// la moins récemment utilisée
+func (cache *matrixCache) put(key uint64, data []complex128, em EncodedMatrix) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	entry := &cachedMatrix{key: key, data: append([]complex128{}, data...), em: em}
+	if elem, ok := cache.entries[key]; ok {
+		elem.Value = entry
+		cache.order.MoveToFront(elem)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	if cache.order.Len() > matrixCacheSize {
+		last := cache.order.Remove(cache.order.Back()).(*cachedMatrix)
+		delete(cache.entries, last.key)
+	}
+}
+
+// retourne le nombre de matrices en cache
+func (cache *matrixCache) len() int {
+	if cache == nil {
+		return 0
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+// indique si <a> et <b> ont les mêmes coefficients
+func equalData(a, b []complex128) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// retourne les diagonales non nulles de la matrice <M> de taille r x c (r, c <= <n>), complétée par des zéros
+// en une matrice n x n : diags[k][j] = M[j][(j+k) mod n]
+func diagonals(M [][]complex128, n int) map[int][]complex128 {
+	diags := make(map[int][]complex128)
+	for k := 0; k < n; k++ {
+		diag := make([]complex128, n)
+		nonZero := false
+		for j := range M {
+			if col := (j + k) % n; col < len(M[j]) {
+				diag[j] = M[j][col]
+				nonZero = nonZero || diag[j] != 0
+			}
+		}
+		if nonZero {
+			diags[k] = diag
+		}
+	}
+	return diags
+}
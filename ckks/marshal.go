@@ -0,0 +1,579 @@
+package ckks
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"kazat.ch/lbcrypto/poly"
+	"kazat.ch/lbcrypto/random"
+)
+
+// Format binaire des objets du package (voir poly.NewFrame pour les cadres)
+// Le contenu commence par la base de l'objet : N, le nombre de premiers de sa base RNS (4 octets chacun)
+// et l'empreinte des paramètres (8 octets), un haché de N et de ces premiers. La base d'un ciphertext
+// est q_0, ..., q_l, celle d'une pk est Q, celle d'une sk ou d'une clé de key switching est Q ∪ P : l'empreinte
+// identifie ainsi les paramètres du schéma jusqu'au niveau de l'objet.
+// UnmarshalBinary vérifie que l'objet est cohérent avec sa base, et CKKS.Load qu'elle est celle de l'instance
+// Le polynôme A d'une PublicKey ou d'un chiffre de SwitchingKey tiré d'une graine (voir uniformNTT)
+// est remplacé par le cadre de sa graine (poly.TagSeed), et de nouveau tiré de la graine à la lecture :
+// la taille des clés est ainsi divisée par deux. Un polynôme est tiré de sa graine par expandSeed : ses limbs, dans
+// l'ordre de sa base, sont lus sur le flux random.PRNG de la graine (AES-256-CTR, compteur initial nul) par
+// rejet de mots de 8 octets (voir random.RandomRNSPolFrom). Chaque graine ne sert qu'à un polynôme : celle de
+// chaque a_j d'une clé de key switching et celle de A sont tirées indépendamment (random.NewSeed), celles des
+// protocoles multiparty sont dérivées de la crs avec le nom du protocole et l'indice du chiffre (deriveSeed)
+
+// retourne l'empreinte de N = <N> et de la base RNS <moduli>
+func fingerprint(N int, moduli []uint64) uint64 {
+	buf := make([]byte, 8*(len(moduli)+1))
+	binary.LittleEndian.PutUint64(buf, uint64(N))
+	for i, q := range moduli {
+		binary.LittleEndian.PutUint64(buf[8*(i+1):], q)
+	}
+	sum := sha256.Sum256(buf)
+	return binary.LittleEndian.Uint64(sum[:8])
+}
+
+// retourne l'empreinte des paramètres de <ckks> (N et la chaîne Q ∪ P complète)
+// deux instances de même empreinte peuvent échanger leurs ciphertexts et leurs clés
+func (ckks *CKKS) Fingerprint() uint64 {
+	return fingerprint(ckks.N, ckks.modulusQP())
+}
+
+// Base d'un objet sérialisé
+type base struct {
+	N           int
+	nbModuli    int
+	fingerprint uint64
+}
+
+// écrit la base d'un objet dont les polynômes sont définis sur <moduli> et de degré < <N>
+func writeBase(w *poly.FrameWriter, N int, moduli []uint64) {
+	w.Uint32(uint32(N))
+	w.Uint32(uint32(len(moduli)))
+	w.Uint64(fingerprint(N, moduli))
+}
+
+func readBase(r *poly.FrameReader) base {
+	return base{N: int(r.Uint32()), nbModuli: int(r.Uint32()), fingerprint: r.Uint64()}
+}
+
+// retourne la base d'un objet de polynômes <pol> (la base vide si <pol> est nul)
+func baseOf(pol poly.RNSPoly) (int, []uint64) {
+	if len(pol.Coefs) == 0 {
+		return 0, nil
+	}
+	return pol.N(), pol.Moduli
+}
+
+// écrit le cadre du polynôme <pol>, qui doit être défini sur la base <moduli>,
+// ou celui de la graine <seed> dont il est tiré si elle n'est pas nil
+func writePoly(w *poly.FrameWriter, pol poly.RNSPoly, seed []byte, N int, moduli []uint64) error {
+	if len(pol.Coefs) == 0 || pol.N() != N || !equalModuli(pol.Moduli, moduli) {
+		return fmt.Errorf("%w : polynomials defined on different bases", ErrInvalidArgument)
+	}
+	if seed != nil {
+		if len(seed) != random.SeedSize {
+			return fmt.Errorf("%w : the seed must have %d bytes, got %d", ErrInvalidArgument, random.SeedSize, len(seed))
+		}
+		w.Bytes(poly.NewFrame(poly.TagSeed, seed))
+		return nil
+	}
+	data, err := pol.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	w.Bytes(data)
+	return nil
+}
+
+// lit le cadre d'un polynôme en forme NTT défini sur la base <b> ; si <moduli> n'est pas nil,
+// sa base doit être <moduli>, sinon son empreinte doit être celle de <b>
+// le cadre d'une graine n'est accepté que si <seeded> est vrai et <moduli> n'est pas nil : le polynôme en est tiré
+// sur <moduli>, et la graine est retournée avec lui (nil pour le cadre d'un polynôme)
+func readPoly(r *poly.FrameReader, b base, moduli []uint64, seeded bool) (poly.RNSPoly, []byte, error) {
+	var pol poly.RNSPoly
+	frame := r.Frame()
+	if err := r.Err(); err != nil {
+		return pol, nil, err
+	}
+	if tag, _, _ := poly.FrameInfo(frame); tag == poly.TagSeed {
+		seed, err := poly.OpenFrame(frame, poly.TagSeed)
+		if err != nil {
+			return pol, nil, err
+		}
+		if !seeded || moduli == nil || len(seed) != random.SeedSize {
+			return pol, nil, fmt.Errorf("%w : invalid seed", ErrFormat)
+		}
+		seed = append([]byte{}, seed...)
+		return expandSeed(b.N, seed, moduli), seed, nil
+	}
+	if err := pol.UnmarshalBinary(frame); err != nil {
+		return pol, nil, err
+	}
+	switch {
+	case len(pol.Coefs) != b.nbModuli || len(pol.Coefs) == 0 || pol.N() != b.N || !pol.IsNTT:
+		return pol, nil, fmt.Errorf("%w : polynomial inconsistent with the object base", ErrFormat)
+	case moduli != nil && !equalModuli(pol.Moduli, moduli):
+		return pol, nil, fmt.Errorf("%w : polynomials defined on different bases", ErrFormat)
+	case moduli == nil && fingerprint(b.N, pol.Moduli) != b.fingerprint:
+		return pol, nil, fmt.Errorf("%w : wrong parameter fingerprint", ErrFormat)
+	}
+	return pol, nil, nil
+}
+
+// Paire de polynômes d'une clé, le second étant tiré de la graine <seed> si elle n'est pas nil
+type keyPair struct {
+	pols [2]poly.RNSPoly
+	seed []byte
+}
+
+// lit les <2*n> polynômes d'une clé formée de <n> paires, définis sur la base <b>
+func readPairs(r *poly.FrameReader, b base, n int) ([]keyPair, error) {
+	var moduli []uint64
+	res := make([]keyPair, 0, n)
+	for i := 0; i < n; i++ {
+		var pair keyPair
+		for j := range pair.pols {
+			pol, seed, err := readPoly(r, b, moduli, j == 1)
+			if err != nil {
+				return nil, err
+			}
+			pair.pols[j], moduli = pol, pol.Moduli
+			if seed != nil {
+				pair.seed = seed
+			}
+		}
+		res = append(res, pair)
+	}
+	return res, nil
+}
+
+// Format d'un CT : la base, l'échelle (parties réelle et imaginaire, 8 octets chacune), un octet d'options
+// (1 si A est tiré de ct.Seed, 2 si le ciphertext est de degré 2), puis le cadre de B, la graine
+// ou le cadre de A, et le cadre de C pour un ciphertext de degré 2
+// Le niveau et le module se déduisent de la base de B. Les coefficients modulo q_i occupent chacun
+// le nombre d'octets de q_i (voir poly.RNSPoly.MarshalBinary)
+
+const (
+	ctSeeded  = 1
+	ctDegree2 = 2
+)
+
+// retourne la représentation binaire du reciever (voir le format ci-dessus) ; si ct.Seed n'est pas nil,
+// A n'est pas écrit et sera tiré de la graine par UnmarshalBinary (A ne doit pas avoir été modifié)
+// ErrInvalidArgument si le reciever est vide ou si ses polynômes sont définis sur des bases différentes
+func (ct CT) MarshalBinary() ([]byte, error) {
+	N, moduli := baseOf(ct.B)
+	if N == 0 {
+		return nil, fmt.Errorf("%w : empty ciphertext", ErrInvalidArgument)
+	}
+	if ct.Seed != nil && len(ct.Seed) != random.SeedSize {
+		return nil, fmt.Errorf("%w : the seed must have %d bytes, got %d", ErrInvalidArgument, random.SeedSize, len(ct.Seed))
+	}
+
+	w := poly.FrameWriter{}
+	writeBase(&w, N, moduli)
+	w.Float64(real(ct.Scale))
+	w.Float64(imag(ct.Scale))
+	flags := byte(0)
+	if ct.Seed != nil {
+		flags |= ctSeeded
+	}
+	if ct.Degree() == 2 {
+		flags |= ctDegree2
+	}
+	w.Byte(flags)
+
+	if err := writePoly(&w, ct.B, nil, N, moduli); err != nil {
+		return nil, err
+	}
+	if ct.Seed != nil {
+		w.Bytes(ct.Seed)
+	} else if err := writePoly(&w, ct.A, nil, N, moduli); err != nil {
+		return nil, err
+	}
+	if ct.Degree() == 2 {
+		if err := writePoly(&w, ct.C, nil, N, moduli); err != nil {
+			return nil, err
+		}
+	}
+	return w.Frame(poly.TagCT), nil
+}
+
+// remplace le reciever par le ciphertext représenté par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'un CT ; les paramètres ne sont pas vérifiés (voir CKKS.Load)
+func (ct *CT) UnmarshalBinary(data []byte) error {
+	payload, err := poly.OpenFrame(data, poly.TagCT)
+	if err != nil {
+		return err
+	}
+	r := poly.NewFrameReader(payload)
+	b := readBase(r)
+	scale := complex(r.Float64(), r.Float64())
+	flags := r.Byte()
+	if flags > ctSeeded|ctDegree2 {
+		return fmt.Errorf("%w : invalid ciphertext flags %d", ErrFormat, flags)
+	}
+
+	B, _, err := readPoly(r, b, nil, false)
+	if err != nil {
+		return err
+	}
+	var A, C poly.RNSPoly
+	var seed []byte
+	if flags&ctSeeded != 0 {
+		seed = append([]byte{}, r.Bytes(random.SeedSize)...)
+		if err := r.Err(); err != nil {
+			return err
+		}
+		A = expandSeed(b.N, seed, B.Moduli)
+	} else if A, _, err = readPoly(r, b, B.Moduli, false); err != nil {
+		return err
+	}
+	if flags&ctDegree2 != 0 {
+		if C, _, err = readPoly(r, b, B.Moduli, false); err != nil {
+			return err
+		}
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+
+	*ct = NewCT(A, B, B.Modulus(), scale, B.Level())
+	ct.C = C
+	ct.Seed = seed
+	return nil
+}
+
+// Format d'une SecretKey ou d'une PublicKey : la base puis les cadres des deux polynômes
+
+// retourne la représentation binaire d'une clé formée de la base puis des polynômes de <pairs>
+func marshalPairs(tag byte, pairs []keyPair) ([]byte, error) {
+	w := poly.FrameWriter{}
+	N, moduli := 0, []uint64(nil)
+	if len(pairs) > 0 {
+		N, moduli = baseOf(pairs[0].pols[0])
+	}
+	writeBase(&w, N, moduli)
+	if tag == poly.TagSwitchingKey {
+		w.Uint32(uint32(len(pairs)))
+	}
+	for _, pair := range pairs {
+		if err := writePoly(&w, pair.pols[0], nil, N, moduli); err != nil {
+			return nil, err
+		}
+		if err := writePoly(&w, pair.pols[1], pair.seed, N, moduli); err != nil {
+			return nil, err
+		}
+	}
+	return w.Frame(tag), nil
+}
+
+// retourne les paires de polynômes d'une clé écrite par marshalPairs ; <n> paires pour une sk ou une pk
+func unmarshalPairs(data []byte, tag byte, n int) ([]keyPair, error) {
+	payload, err := poly.OpenFrame(data, tag)
+	if err != nil {
+		return nil, err
+	}
+	r := poly.NewFrameReader(payload)
+	b := readBase(r)
+	if tag == poly.TagSwitchingKey {
+		n = int(r.Uint32())
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		if n > r.Len() { // chaque paire occupe plus d'un octet
+			return nil, fmt.Errorf("%w : %d key pairs in %d bytes", ErrFormat, n, r.Len())
+		}
+	}
+	pairs, err := readPairs(r, b, n)
+	if err != nil {
+		return nil, err
+	}
+	return pairs, r.Close()
+}
+
+// retourne la représentation binaire de la clé (voir le format ci-dessus)
+func (sk SecretKey) MarshalBinary() ([]byte, error) {
+	return marshalPairs(poly.TagSecretKey, []keyPair{{pols: sk}})
+}
+
+// remplace la clé par celle représentée par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'une SecretKey ; les paramètres ne sont pas vérifiés (voir CKKS.Load)
+func (sk *SecretKey) UnmarshalBinary(data []byte) error {
+	pairs, err := unmarshalPairs(data, poly.TagSecretKey, 1)
+	if err != nil {
+		return err
+	}
+	if pairs[0].seed != nil {
+		return fmt.Errorf("%w : seeded secret key", ErrFormat)
+	}
+	*sk = pairs[0].pols
+	return nil
+}
+
+// retourne la représentation binaire de la clé (voir le format ci-dessus)
+func (pk PublicKey) MarshalBinary() ([]byte, error) {
+	return marshalPairs(poly.TagPublicKey, []keyPair{{pols: [2]poly.RNSPoly{pk.B, pk.A}, seed: pk.Seed}})
+}
+
+// remplace la clé par celle représentée par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'une PublicKey ; les paramètres ne sont pas vérifiés (voir CKKS.Load)
+func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	pairs, err := unmarshalPairs(data, poly.TagPublicKey, 1)
+	if err != nil {
+		return err
+	}
+	*pk = PublicKey{B: pairs[0].pols[0], A: pairs[0].pols[1], Seed: pairs[0].seed}
+	return nil
+}
+
+// Format d'une SwitchingKey : la base, le nombre de chiffres (4 octets), puis les cadres des deux polynômes
+// de chaque chiffre
+
+// retourne la représentation binaire de la clé (voir le format ci-dessus)
+func (swk SwitchingKey) MarshalBinary() ([]byte, error) {
+	pairs := make([]keyPair, len(swk))
+	for j, digit := range swk {
+		pairs[j] = keyPair{pols: [2]poly.RNSPoly{digit.B, digit.A}, seed: digit.Seed}
+	}
+	return marshalPairs(poly.TagSwitchingKey, pairs)
+}
+
+// remplace la clé par celle représentée par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'une SwitchingKey ; les paramètres ne sont pas vérifiés (voir CKKS.Load)
+func (swk *SwitchingKey) UnmarshalBinary(data []byte) error {
+	pairs, err := unmarshalPairs(data, poly.TagSwitchingKey, 0)
+	if err != nil {
+		return err
+	}
+	res := make(SwitchingKey, len(pairs))
+	for j, pair := range pairs {
+		res[j] = SwitchingKeyDigit{B: pair.pols[0], A: pair.pols[1], Seed: pair.seed}
+	}
+	*swk = res
+	return nil
+}
+
+// Une RKGShare s'écrit comme une SwitchingKey dont aucun polynôme n'est tiré d'une graine
+
+// retourne la représentation binaire de la part (voir le format ci-dessus)
+func (share RKGShare) MarshalBinary() ([]byte, error) {
+	pairs := make([]keyPair, len(share))
+	for j, pair := range share {
+		pairs[j] = keyPair{pols: pair}
+	}
+	return marshalPairs(poly.TagSwitchingKey, pairs)
+}
+
+// remplace la part par celle représentée par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'une RKGShare ; les paramètres ne sont pas vérifiés
+func (share *RKGShare) UnmarshalBinary(data []byte) error {
+	pairs, err := unmarshalPairs(data, poly.TagSwitchingKey, 0)
+	if err != nil {
+		return err
+	}
+	res := make(RKGShare, len(pairs))
+	for j, pair := range pairs {
+		if pair.seed != nil {
+			return fmt.Errorf("%w : seeded relinearization key share", ErrFormat)
+		}
+		res[j] = pair.pols
+	}
+	*share = res
+	return nil
+}
+
+// Format de RotationKeys : la base, le nombre de clés (4 octets), puis pour chaque clé par décalage croissant,
+// le décalage (8 octets) et le cadre de la SwitchingKey
+
+// retourne la représentation binaire des clés (voir le format ci-dessus)
+func (keys RotationKeys) MarshalBinary() ([]byte, error) {
+	ks := make([]int, 0, len(keys))
+	for k := range keys {
+		ks = append(ks, k)
+	}
+	sort.Ints(ks)
+
+	N, moduli := 0, []uint64(nil)
+	for _, k := range ks {
+		if len(keys[k]) > 0 {
+			N, moduli = baseOf(keys[k][0].B)
+			break
+		}
+	}
+
+	w := poly.FrameWriter{}
+	writeBase(&w, N, moduli)
+	w.Uint32(uint32(len(ks)))
+	for _, k := range ks {
+		data, err := keys[k].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		w.Uint64(uint64(int64(k)))
+		w.Bytes(data)
+	}
+	return w.Frame(poly.TagRotationKeys), nil
+}
+
+// remplace le reciever par les clés représentées par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation de RotationKeys ; les paramètres ne sont pas vérifiés (voir CKKS.Load)
+func (keys *RotationKeys) UnmarshalBinary(data []byte) error {
+	payload, err := poly.OpenFrame(data, poly.TagRotationKeys)
+	if err != nil {
+		return err
+	}
+	r := poly.NewFrameReader(payload)
+	b := readBase(r)
+	n := int(r.Uint32())
+
+	res := make(RotationKeys)
+	for i := 0; i < n && r.Err() == nil; i++ {
+		k := int(int64(r.Uint64()))
+		frame := r.Frame()
+		if r.Err() != nil {
+			break
+		}
+		var swk SwitchingKey
+		if err := swk.UnmarshalBinary(frame); err != nil {
+			return err
+		}
+		if len(swk) > 0 && (swk[0].B.N() != b.N || fingerprint(b.N, swk[0].B.Moduli) != b.fingerprint) {
+			return fmt.Errorf("%w : rotation keys defined on different bases", ErrFormat)
+		}
+		if _, ok := res[k]; ok {
+			return fmt.Errorf("%w : duplicate rotation key %d", ErrFormat, k)
+		}
+		res[k] = swk
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	*keys = res
+	return nil
+}
+
+// WriteTo et ReadFrom écrivent et lisent la représentation de MarshalBinary
+
+func (ct CT) WriteTo(w io.Writer) (int64, error) {
+	data, err := ct.MarshalBinary()
+	return poly.WriteFrame(w, data, err)
+}
+
+func (ct *CT) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(r, ct)
+}
+
+func (sk SecretKey) WriteTo(w io.Writer) (int64, error) {
+	data, err := sk.MarshalBinary()
+	return poly.WriteFrame(w, data, err)
+}
+
+func (sk *SecretKey) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(r, sk)
+}
+
+func (pk PublicKey) WriteTo(w io.Writer) (int64, error) {
+	data, err := pk.MarshalBinary()
+	return poly.WriteFrame(w, data, err)
+}
+
+func (pk *PublicKey) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(r, pk)
+}
+
+func (swk SwitchingKey) WriteTo(w io.Writer) (int64, error) {
+	data, err := swk.MarshalBinary()
+	return poly.WriteFrame(w, data, err)
+}
+
+func (swk *SwitchingKey) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(r, swk)
+}
+
+func (keys RotationKeys) WriteTo(w io.Writer) (int64, error) {
+	data, err := keys.MarshalBinary()
+	return poly.WriteFrame(w, data, err)
+}
+
+func (keys *RotationKeys) ReadFrom(r io.Reader) (int64, error) {
+	return readFrom(r, keys)
+}
+
+// lit un cadre dans <r> et le décode dans <obj>
+func readFrom(r io.Reader, obj encoding.BinaryUnmarshaler) (int64, error) {
+	data, n, err := poly.ReadFrame(r)
+	if err != nil {
+		return n, err
+	}
+	return n, obj.UnmarshalBinary(data)
+}
+
+// retourne la base de <ckks> sur laquelle doit être défini un objet de type <tag> formé de <nbModuli> premiers :
+// q_0, ..., q_l (l quelconque) pour un ciphertext, Q pour une pk, Q ∪ P pour une sk ou une clé de key switching ;
+// nil si <nbModuli> ne convient pas au type
+func (ckks *CKKS) baseModuli(tag byte, nbModuli int) []uint64 {
+	switch tag {
+	case poly.TagCT:
+		if nbModuli >= 1 && nbModuli <= len(ckks.Moduli) {
+			return ckks.Moduli[:nbModuli]
+		}
+	case poly.TagPublicKey:
+		if nbModuli == len(ckks.Moduli) {
+			return ckks.Moduli
+		}
+	case poly.TagSecretKey, poly.TagSwitchingKey, poly.TagRotationKeys:
+		if nbModuli == len(ckks.Moduli)+len(ckks.SpecialModuli) {
+			return ckks.modulusQP()
+		}
+	}
+	return nil
+}
+
+// décode dans <obj> (un *CT, *SecretKey, *PublicKey, *SwitchingKey, *RKGShare ou *RotationKeys) la représentation <data>
+// écrite par MarshalBinary, après avoir vérifié que son empreinte est celle des paramètres de <ckks>
+// et que l'objet est défini sur la base de son type (voir baseModuli)
+// ErrParameterMismatch si l'objet a été sérialisé par une instance de paramètres différents, ErrFormat si <data>
+// n'est pas une représentation valide, ou si l'objet est défini sur une autre base de <ckks> (une clé tronquée)
+func (ckks *CKKS) Load(data []byte, obj encoding.BinaryUnmarshaler) error {
+	tag, _, err := poly.FrameInfo(data)
+	if err != nil {
+		return err
+	}
+	if tag < poly.TagCT || tag > poly.TagRotationKeys {
+		return fmt.Errorf("%w : object of type %d does not depend on the parameters", ErrFormat, tag)
+	}
+	r := poly.NewFrameReader(data[poly.FrameHeaderSize:])
+	b := readBase(r)
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if b.nbModuli == 0 {
+		if tag == poly.TagSwitchingKey || tag == poly.TagRotationKeys { // des clés vides ne dépendent pas des paramètres
+			return obj.UnmarshalBinary(data)
+		}
+		return fmt.Errorf("%w : empty object of type %d", ErrFormat, tag)
+	}
+	if moduli := ckks.baseModuli(tag, b.nbModuli); b.N != ckks.N || moduli == nil || fingerprint(ckks.N, moduli) != b.fingerprint {
+		qp := ckks.modulusQP()
+		if b.N == ckks.N && b.nbModuli <= len(qp) && fingerprint(ckks.N, qp[:b.nbModuli]) == b.fingerprint {
+			return fmt.Errorf("%w : object of type %d defined on %d of the %d moduli", ErrFormat, tag, b.nbModuli, len(qp))
+		}
+		return fmt.Errorf("%w : fingerprint %016x does not match the parameters", ErrParameterMismatch, b.fingerprint)
+	}
+	return obj.UnmarshalBinary(data)
+}
+
+// comme Load, l'objet étant lu dans <r> (voir les méthodes WriteTo) ; retourne le nombre d'octets lus
+func (ckks *CKKS) LoadFrom(r io.Reader, obj encoding.BinaryUnmarshaler) (int64, error) {
+	data, n, err := poly.ReadFrame(r)
+	if err != nil {
+		return n, err
+	}
+	return n, ckks.Load(data, obj)
+}
@@ -0,0 +1,296 @@
+package ckks
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"kazat.ch/lbcrypto/encoder"
+	"kazat.ch/lbcrypto/poly"
+	"kazat.ch/lbcrypto/random"
+)
+
+// Protocoles à n parties (n-out-of-n, Mouchet et al., "Multiparty Homomorphic Encryption from Ring-Learning-with-Errors", 2021)
+// Chaque partie i tire sa propre sk (1, s_i) avec SKeyGen. La sk collective (1, s), avec s = s_1 + ... + s_n,
+// n'est connue de personne : déchiffrer demande la participation des n parties.
+// Les polynômes uniformes communs sont tirés d'une graine publique <crs> de random.SeedSize octets (common reference
+// string), connue de toutes les parties ; chaque protocole en dérive ses propres graines.
+// Les parts échangées se sérialisent comme les clés (PublicKey, RKGShare) et les polynômes (poly.RNSPoly)
+
+// retourne la graine du <j>-ième polynôme commun du protocole <label>, dérivée de la graine commune <crs> :
+// SHA-256(crs || taille de <label> || <label> || j sur 2 octets) ; la taille du libellé rend l'encodage injectif,
+// deux couples (libellé, indice) distincts donnent donc des graines indépendantes
+func deriveSeed(crs []byte, label string, j int) []byte {
+	data := append(append([]byte{}, crs...), byte(len(label)))
+	data = append(append(data, label...), byte(j>>8), byte(j))
+	sum := sha256.Sum256(data)
+	return sum[:random.SeedSize]
+}
+
+// retourne ErrInvalidArgument si <crs> n'a pas random.SeedSize octets
+func checkCRS(crs []byte) error {
+	if len(crs) != random.SeedSize {
+		return fmt.Errorf("%w : the common reference string must have %d bytes, got %d", ErrInvalidArgument, random.SeedSize, len(crs))
+	}
+	return nil
+}
+
+// indique si <u> et <v> sont le même polynôme
+func sameRNS(u, v poly.RNSPoly) bool {
+	if u.IsNTT != v.IsNTT || !equalModuli(u.Moduli, v.Moduli) || len(u.Coefs) != len(v.Coefs) {
+		return false
+	}
+	for i := range u.Coefs {
+		if len(u.Coefs[i]) != len(v.Coefs[i]) {
+			return false
+		}
+		for j := range u.Coefs[i] {
+			if u.Coefs[i][j] != v.Coefs[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/* ----------------------- clé publique collective ----------------------- */
+
+// retourne la part (-a*s_i + e_i, a) de la partie de sk <sk> dans la public key collective, où a est tiré de <crs>
+// c'est la public key de PKeyGen pour ce a commun
+// ErrInvalidArgument si <crs> n'a pas random.SeedSize octets
+func (ckks *CKKS) PKeyGenShare(sk [2]poly.RNSPoly, crs []byte) (PublicKey, error) {
+	if err := checkCRS(crs); err != nil {
+		return PublicKey{}, err
+	}
+	seed := deriveSeed(crs, "pk", 0)
+	return ckks.pKeyGen(sk, expandSeed(ckks.N, seed, ckks.Moduli), seed), nil
+}
+
+// retourne la public key collective (-a*s + e, a), somme des parts <shares> de toutes les parties
+// ErrInvalidArgument s'il n'y a aucune part ou si elles n'ont pas le même a
+func (ckks *CKKS) AggregatePublicKey(shares ...PublicKey) (PublicKey, error) {
+	if len(shares) == 0 {
+		return PublicKey{}, fmt.Errorf("%w : no public key share", ErrInvalidArgument)
+	}
+	pk := shares[0]
+	for _, share := range shares[1:] {
+		if !sameRNS(share.A, pk.A) {
+			return PublicKey{}, fmt.Errorf("%w : public key shares drawn from different common polynomials", ErrInvalidArgument)
+		}
+		pk.B = poly.AddRNS(pk.B, share.B)
+	}
+	return pk, nil
+}
+
+/* ------------------- clé de relinéarisation collective ------------------- */
+
+// Part d'une partie dans la génération de la clé de relinéarisation collective : une paire par chiffre de la
+// décomposition de Q, définie modulo Q * P ; les parts d'un même tour s'additionnent avec AggregateRKGShares
+type RKGShare [][2]poly.RNSPoly
+
+// retourne la part du premier tour de la partie de sk <sk>, et son secret éphémère u_i (à garder pour le second tour)
+// pour chaque chiffre j, avec a_j tiré de <crs> : (-u_i*a_j + P*g_j*s_i + e, s_i*a_j + e'),
+// c'est-à-dire une clé de key switching de s_i vers u_i (voir switchingKeyGen) accompagnée d'un chiffré de s_i
+// ErrInvalidArgument si <crs> n'a pas random.SeedSize octets
+func (ckks *CKKS) RKGShareRound1(sk [2]poly.RNSPoly, crs []byte) (RKGShare, SecretKey, error) {
+	if err := checkCRS(crs); err != nil {
+		return nil, SecretKey{}, err
+	}
+	moduli := ckks.modulusQP()
+	as, seeds := []poly.RNSPoly{}, [][]byte{}
+	for j := range ckks.digits(ckks.L) {
+		seed := deriveSeed(crs, "rkg", j)
+		as, seeds = append(as, expandSeed(ckks.N, seed, moduli)), append(seeds, seed)
+	}
+
+	u := ckks.SKeyGen()
+	h0 := ckks.switchingKeyGenWith(sk[1], u[1], as, seeds)
+
+	share := RKGShare{}
+	for j, a := range as {
+		e := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), moduli)
+		h1 := poly.AddRNS(poly.MultModRNS(a, sk[1]), e)
+		share = append(share, [2]poly.RNSPoly{h0[j].B, h1})
+	}
+	return share, u, nil
+}
+
+// retourne la part du second tour de la partie de sk <sk> et de secret éphémère <u>, à partir de la somme <round1>
+// des parts du premier tour (h0_j, h1_j) : (s_i*h0_j + e, (u_i - s_i)*h1_j + e')
+// ErrInvalidArgument si <round1> n'a pas un chiffre par chiffre de la décomposition de Q
+func (ckks *CKKS) RKGShareRound2(sk, u [2]poly.RNSPoly, round1 RKGShare) (RKGShare, error) {
+	if err := ckks.checkRKGShares(round1); err != nil {
+		return nil, err
+	}
+	moduli := ckks.modulusQP()
+	uMinusS := poly.SubRNS(u[1], sk[1])
+
+	share := RKGShare{}
+	for _, pair := range round1 {
+		e0 := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), moduli)
+		e1 := ckks.toRNS(random.DG(ckks.N, ckks.s2, ckks.Q), moduli)
+		h0 := poly.AddRNS(poly.MultModRNS(pair[0], sk[1]), e0)
+		h1 := poly.AddRNS(poly.MultModRNS(pair[1], uMinusS), e1)
+		share = append(share, [2]poly.RNSPoly{h0, h1})
+	}
+	return share, nil
+}
+
+// retourne ErrInvalidArgument si les parts <shares> n'ont pas une paire définie modulo Q * P par chiffre
+func (ckks *CKKS) checkRKGShares(shares ...RKGShare) error {
+	nbDigits, moduli := len(ckks.digits(ckks.L)), ckks.modulusQP()
+	for _, share := range shares {
+		if len(share) != nbDigits {
+			return fmt.Errorf("%w : relinearization key share with %d digits instead of %d", ErrInvalidArgument, len(share), nbDigits)
+		}
+		for _, pair := range share {
+			if !equalModuli(pair[0].Moduli, moduli) || !equalModuli(pair[1].Moduli, moduli) {
+				return fmt.Errorf("%w : relinearization key share not defined modulo Q * P", ErrInvalidArgument)
+			}
+		}
+	}
+	return nil
+}
+
+// retourne la somme des parts <shares> d'un même tour
+// ErrInvalidArgument s'il n'y a aucune part ou si elles ne sont pas des parts de <ckks>
+func (ckks *CKKS) AggregateRKGShares(shares ...RKGShare) (RKGShare, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("%w : no relinearization key share", ErrInvalidArgument)
+	}
+	if err := ckks.checkRKGShares(shares...); err != nil {
+		return nil, err
+	}
+	res := append(RKGShare{}, shares[0]...)
+	for _, share := range shares[1:] {
+		for j := range res {
+			res[j] = [2]poly.RNSPoly{poly.AddRNS(res[j][0], share[j][0]), poly.AddRNS(res[j][1], share[j][1])}
+		}
+	}
+	return res, nil
+}
+
+// retourne la clé de relinéarisation collective, utilisable comme une evaluation key de EvKeyGen pour la sk collective,
+// à partir des sommes <round1> et <round2> des parts des deux tours : (h0'_j + h1'_j, h1_j)
+// en effet h0'_j + h1'_j + h1_j*s = s*h0_j + u*h1_j + e = P*g_j*s^2 + e'
+// ErrInvalidArgument si les sommes ne sont pas des parts de <ckks>
+func (ckks *CKKS) RelinearizationKey(round1, round2 RKGShare) (SwitchingKey, error) {
+	if err := ckks.checkRKGShares(round1, round2); err != nil {
+		return nil, err
+	}
+	evk := SwitchingKey{}
+	for j := range round1 {
+		evk = append(evk, SwitchingKeyDigit{B: poly.AddRNS(round2[j][0], round2[j][1]), A: round1[j][1]})
+	}
+	return evk, nil
+}
+
+/* ------------------------ déchiffrement collectif ------------------------ */
+// Premier tour : chaque partie publie sa part de déchiffrement A*s_i + e_i (DecryptionShare).
+// Second tour : les parts sont additionnées (AggregateDecryptionShares) et la somme, ajoutée à B, donne le plaintext
+// B + A*s + e (CombineDecryption). Le bruit de lissage e_i (smudging) masque le secret s_i et le bruit du ciphertext,
+// qu'une part sans bruit révélerait avec le plaintext
+
+// écart-type maximal du bruit de lissage, pour que DG l'échantillonne sur un int64
+const maxSmudgingStd = 1 << 50
+
+// retourne la part de déchiffrement A*s_i + e_i de la partie de sk <sk> pour le ciphertext <ct>,
+// e_i étant gaussien d'écart-type <smudgingStd>
+// <smudgingStd> doit être grand devant le bruit de <ct> et petit devant son échelle, dont il limite la précision
+// ErrNotRelinearized si <ct> est de degré 2, ErrInvalidArgument si <smudgingStd> n'est pas dans [0, 2^50]
+func (ckks *CKKS) DecryptionShare(ct CT, sk [2]poly.RNSPoly, smudgingStd float64) (poly.RNSPoly, error) {
+	if err := ckks.checkDegree(ct); err != nil {
+		return poly.RNSPoly{}, err
+	}
+	if !(smudgingStd >= 0 && smudgingStd <= maxSmudgingStd) {
+		return poly.RNSPoly{}, fmt.Errorf("%w : the smudging standard deviation must be in [0, 2^50], got %g", ErrInvalidArgument, smudgingStd)
+	}
+	s := poly.DropLimbs(sk[1], ct.L)
+	// le bruit de lissage protège sk : il est tiré d'un flux imprévisible, et non du générateur de math/rand
+	e := ckks.toRNS(random.DGFrom(ckks.N, smudgingStd*smudgingStd, ckks.Q, random.NewPRNG(random.NewSeed())), ct.B.Moduli)
+	return poly.AddRNS(poly.MultModRNS(ct.A, s), e), nil
+}
+
+// retourne la somme des parts de déchiffrement <shares> de toutes les parties
+// ErrInvalidArgument s'il n'y a aucune part, ErrLevelMismatch si elles ne sont pas au même niveau
+func (ckks *CKKS) AggregateDecryptionShares(shares ...poly.RNSPoly) (poly.RNSPoly, error) {
+	if len(shares) == 0 {
+		return poly.RNSPoly{}, fmt.Errorf("%w : no decryption share", ErrInvalidArgument)
+	}
+	res := shares[0]
+	for _, share := range shares[1:] {
+		if !equalModuli(share.Moduli, res.Moduli) {
+			return poly.RNSPoly{}, fmt.Errorf("%w : decryption shares at levels %d and %d", ErrLevelMismatch, res.Level(), share.Level())
+		}
+		res = poly.AddRNS(res, share)
+	}
+	return res, nil
+}
+
+// retourne le plaintext B + <share> du ciphertext <ct>, où <share> est la somme des parts de déchiffrement
+// de toutes les parties pour <ct> : c'est le résultat de Decrypt sous la sk collective, au bruit de lissage près
+// ErrNotRelinearized si <ct> est de degré 2, ErrLevelMismatch si <share> n'est pas au niveau de <ct>
+func (ckks *CKKS) CombineDecryption(ct CT, share poly.RNSPoly) (encoder.PT, error) {
+	if err := ckks.checkDegree(ct); err != nil {
+		return encoder.PT{}, err
+	}
+	if !equalModuli(share.Moduli, ct.B.Moduli) || !share.IsNTT {
+		return encoder.PT{}, fmt.Errorf("%w : decryption share at level %d for a ciphertext at level %d", ErrLevelMismatch, share.Level(), ct.L)
+	}
+	pt := poly.AddRNS(ct.B, share)
+	return encoder.PT{Pol: pt.ToPoly(), Scale: ct.Scale}, nil
+}
+
+/* ------------------ changement de clé vers une clé publique ------------------ */
+// Les parties ramènent un ciphertext chiffré sous la sk collective à un ciphertext chiffré sous la public key
+// <pkOut> d'un destinataire (produite par PKeyGen), sans déchiffrement : seul le destinataire peut lire le résultat.
+// Chaque partie publie sa part (PublicKeySwitchShare), puis les parts sont combinées (AggregatePublicKeySwitch)
+
+// Part d'une partie dans le changement de clé d'un ciphertext vers une public key
+type PCKSShare [2]poly.RNSPoly
+
+// retourne la part (s_i*A + u_i*p0 + e0, u_i*p1 + e1) de la partie de sk <sk> pour passer le ciphertext <ct>
+// sous la public key <pkOut> = (p0, p1), u_i étant tiré comme dans Encrypt et e0 étant un bruit de lissage
+// d'écart-type <smudgingStd> (voir DecryptionShare)
+// ErrNotRelinearized si <ct> est de degré 2, ErrInvalidArgument si <pkOut> n'est pas une public key de <ckks>
+// ou si <smudgingStd> n'est pas dans [0, 2^50]
+func (ckks *CKKS) PublicKeySwitchShare(ct CT, sk [2]poly.RNSPoly, pkOut PublicKey, smudgingStd float64) (PCKSShare, error) {
+	h0, err := ckks.DecryptionShare(ct, sk, smudgingStd)
+	if err != nil {
+		return PCKSShare{}, err
+	}
+	if !equalModuli(pkOut.B.Moduli, ckks.Moduli) || !equalModuli(pkOut.A.Moduli, ckks.Moduli) {
+		return PCKSShare{}, fmt.Errorf("%w : the public key is not defined modulo Q", ErrInvalidArgument)
+	}
+	moduli := ct.B.Moduli
+	p0, p1 := poly.DropLimbs(pkOut.B, ct.L), poly.DropLimbs(pkOut.A, ct.L)
+	rng := random.NewPRNG(random.NewSeed()) // u_i et e1 masquent h0 : ils doivent être imprévisibles (voir DecryptionShare)
+	u := ckks.toRNS(random.ZOFrom(ckks.N, 0.5, rng), moduli)
+	e1 := ckks.toRNS(random.DGFrom(ckks.N, ckks.s2, ckks.Q, rng), moduli)
+
+	h0 = poly.AddRNS(h0, poly.MultModRNS(u, p0))
+	h1 := poly.AddRNS(poly.MultModRNS(u, p1), e1)
+	return PCKSShare{h0, h1}, nil
+}
+
+// retourne le ciphertext (B + h0, h1) chiffrant le message de <ct> sous la public key des parts, où (h0, h1)
+// est la somme des parts <shares> de toutes les parties
+// ErrInvalidArgument s'il n'y a aucune part, ErrNotRelinearized si <ct> est de degré 2,
+// ErrLevelMismatch si les parts ne sont pas au niveau de <ct>
+func (ckks *CKKS) AggregatePublicKeySwitch(ct CT, shares ...PCKSShare) (CT, error) {
+	if len(shares) == 0 {
+		return CT{}, fmt.Errorf("%w : no key switching share", ErrInvalidArgument)
+	}
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	h0, h1 := ct.B, poly.NewRNSPoly(ckks.N, ct.B.Moduli)
+	h1.IsNTT = true
+	for _, share := range shares {
+		if !equalModuli(share[0].Moduli, ct.B.Moduli) || !equalModuli(share[1].Moduli, ct.B.Moduli) {
+			return CT{}, fmt.Errorf("%w : key switching share at level %d for a ciphertext at level %d", ErrLevelMismatch, share[0].Level(), ct.L)
+		}
+		h0 = poly.AddRNS(h0, share[0])
+		h1 = poly.AddRNS(h1, share[1])
+	}
+	return NewCT(h1, h0, ct.Mod, ct.Scale, ct.L), nil
+}
@@ -0,0 +1,198 @@
+package ckks
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Jeux de paramètres standard et estimation de leur sécurité
+// La sécurité de RLWE dépend de N, de la taille du plus grand module utilisé (Q * P pour les clés d'évaluation),
+// de la distribution du secret et de celle de l'erreur. Les tables du standard HomomorphicEncryption.org
+// (Albrecht et al., "Homomorphic Encryption Security Standard", 2018) donnent, pour un secret ternaire
+// et une erreur gaussienne d'écart-type 3.2, la taille maximale de log(QP) pour chaque niveau de sécurité.
+// Elles s'arrêtent à N = 2^15 : pour N = 2^16, on double les valeurs de N = 2^15 (à sécurité fixée, log(QP)
+// maximal croît un peu plus vite que N dans les tables, ce qui rend l'extrapolation prudente).
+
+// Niveau de sécurité classique, en bits
+type SecurityLevel int
+
+const (
+	Security128 SecurityLevel = 128
+	Security192 SecurityLevel = 192
+	Security256 SecurityLevel = 256
+)
+
+// log2 de N minimal et maximal couverts par la table
+const (
+	MinLogN = 10
+	MaxLogN = 16
+)
+
+// écart-type de l'erreur supposé par le standard : la variance s2 doit être au moins standardStd^2
+const standardStd = 3.2
+
+// maxLogQP[security][logN - MinLogN] : taille maximale en bits de Q * P
+var maxLogQP = map[SecurityLevel][MaxLogN - MinLogN + 1]int{
+	Security128: {27, 54, 109, 218, 438, 881, 1762},
+	Security192: {19, 37, 75, 152, 305, 611, 1222},
+	Security256: {14, 29, 58, 118, 237, 476, 952},
+}
+
+// Paramètres d'une instance du schéma, à passer à NewCKKSFromParameters
+// La chaîne de modules est formée d'un premier q_0 de LogQ0 bits et de L premiers de LogDelta bits,
+// le key switching utilise Dnum chiffres et des premiers spéciaux de LogP bits
+type Parameters struct {
+	LogN     int
+	LogQ0    int
+	LogDelta int
+	L        int
+	Dnum     int
+	LogP     int
+	H        int           // poids de Hamming de la sk
+	S2       float64       // variance de l'erreur gaussienne
+	Security SecurityLevel // niveau de sécurité exigé
+}
+
+// Jeux de paramètres standard, nommés par log2(N) et la taille maximale de log(QP) de la table :
+// q_0 a 20 bits de plus que les premiers q_1, ..., q_L (partie entière des messages), la sk est de poids N/2,
+// et L est le plus grand possible avec au plus 3 chiffres pour le key switching (pour limiter la taille des clés)
+// aucun jeu n'est proposé pour N <= 2^11 (et N = 2^12 au-delà de 128 bits) : log(QP) y est trop petit
+// pour q_0, un premier q_1 d'au moins 20 bits et P
+var (
+	PN12QP109  = Parameters{LogN: 12, LogQ0: 40, LogDelta: 20, L: 1, Dnum: 2, LogP: 41, H: 1 << 11, S2: standardStd * standardStd, Security: Security128}
+	PN13QP218  = Parameters{LogN: 13, LogQ0: 60, LogDelta: 40, L: 2, Dnum: 3, LogP: 61, H: 1 << 12, S2: standardStd * standardStd, Security: Security128}
+	PN14QP438  = Parameters{LogN: 14, LogQ0: 60, LogDelta: 40, L: 5, Dnum: 3, LogP: 61, H: 1 << 13, S2: standardStd * standardStd, Security: Security128}
+	PN15QP881  = Parameters{LogN: 15, LogQ0: 60, LogDelta: 40, L: 14, Dnum: 3, LogP: 61, H: 1 << 14, S2: standardStd * standardStd, Security: Security128}
+	PN16QP1762 = Parameters{LogN: 16, LogQ0: 60, LogDelta: 40, L: 30, Dnum: 3, LogP: 61, H: 1 << 15, S2: standardStd * standardStd, Security: Security128}
+
+	PN13QP152  = Parameters{LogN: 13, LogQ0: 55, LogDelta: 35, L: 1, Dnum: 2, LogP: 56, H: 1 << 12, S2: standardStd * standardStd, Security: Security192}
+	PN14QP305  = Parameters{LogN: 14, LogQ0: 60, LogDelta: 40, L: 3, Dnum: 2, LogP: 61, H: 1 << 13, S2: standardStd * standardStd, Security: Security192}
+	PN15QP611  = Parameters{LogN: 15, LogQ0: 60, LogDelta: 40, L: 9, Dnum: 3, LogP: 61, H: 1 << 14, S2: standardStd * standardStd, Security: Security192}
+	PN16QP1222 = Parameters{LogN: 16, LogQ0: 60, LogDelta: 40, L: 20, Dnum: 3, LogP: 61, H: 1 << 15, S2: standardStd * standardStd, Security: Security192}
+
+	PN13QP118 = Parameters{LogN: 13, LogQ0: 45, LogDelta: 25, L: 1, Dnum: 2, LogP: 46, H: 1 << 12, S2: standardStd * standardStd, Security: Security256}
+	PN14QP237 = Parameters{LogN: 14, LogQ0: 60, LogDelta: 40, L: 2, Dnum: 3, LogP: 61, H: 1 << 13, S2: standardStd * standardStd, Security: Security256}
+	PN15QP476 = Parameters{LogN: 15, LogQ0: 60, LogDelta: 40, L: 5, Dnum: 2, LogP: 61, H: 1 << 14, S2: standardStd * standardStd, Security: Security256}
+	PN16QP952 = Parameters{LogN: 16, LogQ0: 60, LogDelta: 40, L: 14, Dnum: 3, LogP: 61, H: 1 << 15, S2: standardStd * standardStd, Security: Security256}
+)
+
+// retourne le jeu de paramètres standard pour N = 2^<logN> et le niveau de sécurité <security>
+// ErrInvalidArgument s'il n'y en a pas (voir les jeux PN...)
+func DefaultParameters(logN int, security SecurityLevel) (Parameters, error) {
+	for _, params := range []Parameters{
+		PN12QP109, PN13QP218, PN14QP438, PN15QP881, PN16QP1762,
+		PN13QP152, PN14QP305, PN15QP611, PN16QP1222,
+		PN13QP118, PN14QP237, PN15QP476, PN16QP952,
+	} {
+		if params.LogN == logN && params.Security == security {
+			return params, nil
+		}
+	}
+	return Parameters{}, fmt.Errorf("%w : no standard parameters for N = 2^%d at %d-bit security", ErrInvalidArgument, logN, security)
+}
+
+// retourne la taille maximale en bits de Q * P pour N = 2^<logN> au niveau de sécurité <security>
+// ErrInvalidArgument si <logN> ou <security> ne sont pas dans la table
+func MaxLogQP(logN int, security SecurityLevel) (int, error) {
+	table, ok := maxLogQP[security]
+	if !ok {
+		return 0, fmt.Errorf("%w : unknown security level %d", ErrInvalidArgument, security)
+	}
+	if logN < MinLogN || logN > MaxLogN {
+		return 0, fmt.Errorf("%w : log N must be between %d and %d, got %d", ErrInvalidArgument, MinLogN, MaxLogN, logN)
+	}
+	return table[logN-MinLogN], nil
+}
+
+// retourne le plus haut niveau de sécurité de la table atteint par N = 2^<logN> et un module QP de <logQP> bits,
+// 0 s'il est inférieur à 128 bits ou si <logN> n'est pas dans la table
+func EstimateSecurity(logN, logQP int) SecurityLevel {
+	res := SecurityLevel(0)
+	for _, security := range []SecurityLevel{Security128, Security192, Security256} {
+		if max, err := MaxLogQP(logN, security); err == nil && logQP <= max {
+			res = security
+		}
+	}
+	return res
+}
+
+// retourne une borne supérieure sur la taille en bits de Q * P : les premiers de b bits sont inférieurs à 2^b,
+// et le nombre de premiers spéciaux est celui choisi par NewCKKSFromParameters
+func (params Parameters) LogQP() int {
+	logQ := params.LogQ0 + params.L*params.LogDelta
+
+	// chiffres de (L + Dnum) / Dnum premiers consécutifs, comme CKKS.digits
+	alpha := (params.L + params.Dnum) / params.Dnum
+	maxDigitBits := 0
+	for start := 0; start <= params.L; start += alpha {
+		bits := 0
+		for i := start; i < start+alpha && i <= params.L; i++ {
+			if i == 0 {
+				bits += params.LogQ0
+			} else {
+				bits += params.LogDelta
+			}
+		}
+		if bits > maxDigitBits {
+			maxDigitBits = bits
+		}
+	}
+	nbSpecial := (maxDigitBits + params.LogP - 2) / (params.LogP - 1)
+
+	return logQ + nbSpecial*params.LogP
+}
+
+// retourne la sécurité estimée de <params> (voir EstimateSecurity) ; 0 si la sk est creuse (H < N/2)
+// ou si l'erreur est plus petite que celle du standard, les tables ne s'appliquant alors pas
+func (params Parameters) EstimatedSecurity() SecurityLevel {
+	if 2*params.H < 1<<params.LogN || params.S2 < standardStd*standardStd {
+		return 0
+	}
+	return EstimateSecurity(params.LogN, params.LogQP())
+}
+
+// retourne une erreur si <params> ne décrit pas une instance du schéma (ErrInvalidArgument)
+// ou si sa sécurité estimée est inférieure à params.Security (ErrInsecureParameters)
+func (params Parameters) Validate() error {
+
+	if params.LogN < 2 || params.LogN > 30 {
+		return fmt.Errorf("%w : log N must be between 2 and 30, got %d", ErrInvalidArgument, params.LogN)
+	}
+	if err := checkParameters(1<<params.LogN, params.H, params.L, params.LogQ0, params.LogDelta, params.Dnum, params.LogP, params.S2); err != nil {
+		return err
+	}
+
+	if _, err := MaxLogQP(params.LogN, params.Security); err != nil {
+		return err
+	}
+	if 2*params.H < 1<<params.LogN {
+		return fmt.Errorf("%w : the security tables do not apply to a sparse secret (H = %d < N/2)", ErrInsecureParameters, params.H)
+	}
+	if params.S2 < standardStd*standardStd {
+		return fmt.Errorf("%w : the error variance %g is below %g", ErrInsecureParameters, params.S2, standardStd*standardStd)
+	}
+	if security := params.EstimatedSecurity(); security < params.Security {
+		max, _ := MaxLogQP(params.LogN, params.Security)
+		return fmt.Errorf("%w : log QP = %d exceeds %d for N = 2^%d at %d-bit security", ErrInsecureParameters, params.LogQP(), max, params.LogN, params.Security)
+	}
+	return nil
+}
+
+// retourne une instance du schéma décrite par <params>, après avoir vérifié qu'elle atteint params.Security
+// (voir Validate) ; ErrInvalidArgument s'il n'y a pas assez de premiers des tailles demandées
+func NewCKKSFromParameters(params Parameters) (CKKS, error) {
+	if err := params.Validate(); err != nil {
+		return CKKS{}, err
+	}
+	return newCKKS(1<<params.LogN, params.H, params.L, params.LogQ0, params.LogDelta, params.Dnum, params.LogP, params.S2)
+}
+
+// retourne la sécurité estimée de l'instance <ckks>, d'après la taille de Q * P (voir Parameters.EstimatedSecurity)
+func (ckks *CKKS) EstimatedSecurity() SecurityLevel {
+	if 2*ckks.H < ckks.N || ckks.s2 < standardStd*standardStd {
+		return 0
+	}
+	logQP := new(big.Int).Mul(ckks.Q, ckks.P).BitLen()
+	return EstimateSecurity(bits.Len(uint(ckks.N))-1, logQP)
+}
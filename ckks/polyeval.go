@@ -0,0 +1,319 @@
+package ckks
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// Base dans laquelle sont donnés les coefficients d'un polynôme
+type Basis int
+
+const (
+	MonomialBasis  Basis = iota // p(x) = sum_k c_k x^k
+	ChebyshevBasis              // p(x) = sum_k c_k T_k(u), où u = (2x - a - b) / (b - a) pour x dans [a, b]
+)
+
+// Polynôme à coefficients réels, évaluable sur un ciphertext par EvalPoly
+type Polynomial struct {
+	Coeffs   []float64
+	Basis    Basis
+	Interval [2]float64 // intervalle [a, b] de la base de Chebyshev (ignoré pour la base monomiale)
+}
+
+// retourne le polynôme sum_k coeffs[k] x^k
+func NewMonomialPolynomial(coeffs []float64) Polynomial {
+	return Polynomial{Coeffs: coeffs, Basis: MonomialBasis, Interval: [2]float64{-1, 1}}
+}
+
+// retourne le polynôme sum_k coeffs[k] T_k(u) sur l'intervalle [<a>, <b>]
+// ErrInvalidArgument si l'intervalle est vide (voir checkInterval)
+func NewChebyshevPolynomial(coeffs []float64, a, b float64) (Polynomial, error) {
+	if err := checkInterval(a, b); err != nil {
+		return Polynomial{}, err
+	}
+	return Polynomial{Coeffs: coeffs, Basis: ChebyshevBasis, Interval: [2]float64{a, b}}, nil
+}
+
+// retourne ErrInvalidArgument si [<a>, <b>] n'est pas un intervalle fini non vide
+func checkInterval(a, b float64) error {
+	if !(a < b) || math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return fmt.Errorf("%w : the interval must be [a, b] with a < b finite, got [%g, %g]", ErrInvalidArgument, a, b)
+	}
+	return nil
+}
+
+// retourne l'interpolation de <f> de degré <degree> aux noeuds de Chebyshev de [<a>, <b>]
+// les coefficients négligeables (relativement au plus grand) sont mis à 0, ce qui évite des produits inutiles
+// ErrInvalidArgument si <degree> est négatif ou si l'intervalle est vide
+func ChebyshevApproximation(f func(float64) float64, degree int, a, b float64) (Polynomial, error) {
+	if degree < 0 {
+		return Polynomial{}, fmt.Errorf("%w : negative degree %d", ErrInvalidArgument, degree)
+	}
+	if err := checkInterval(a, b); err != nil {
+		return Polynomial{}, err
+	}
+	return chebyshevApproximation(f, degree, a, b), nil
+}
+
+// comme ChebyshevApproximation, pour des arguments valides
+func chebyshevApproximation(f func(float64) float64, degree int, a, b float64) Polynomial {
+	n := degree + 1
+	c := make([]float64, n)
+	for k := range c {
+		for j := 0; j < n; j++ {
+			theta := math.Pi * (float64(j) + 0.5) / float64(n)
+			x := (a+b)/2 + (b-a)/2*math.Cos(theta)
+			c[k] += f(x) * math.Cos(float64(k)*theta)
+		}
+		c[k] *= 2 / float64(n)
+	}
+	c[0] /= 2
+
+	max := 0.0
+	for _, ck := range c {
+		max = math.Max(max, math.Abs(ck))
+	}
+	for k := range c {
+		if math.Abs(c[k]) < 1e-14*max {
+			c[k] = 0
+		}
+	}
+	return Polynomial{Coeffs: c, Basis: ChebyshevBasis, Interval: [2]float64{a, b}}
+}
+
+// retourne le degré du polynôme (0 pour un polynôme constant ou nul)
+func (p Polynomial) Degree() int {
+	return degree(p.Coeffs)
+}
+
+// retourne le nombre de niveaux consommés par EvalPoly : ceil(log2(degree + 1)),
+// plus un niveau pour ramener [a, b] sur [-1, 1] dans la base de Chebyshev si [a, b] != [-1, 1]
+func (p Polynomial) Depth() int {
+	depth := optimalDepth(p.Degree())
+	if p.needsRescaling() {
+		depth++
+	}
+	return depth
+}
+
+// retourne la valeur du polynôme en <x> (en clair)
+func (p Polynomial) Eval(x float64) float64 {
+	res := 0.0
+	if p.Basis == MonomialBasis {
+		for k := len(p.Coeffs) - 1; k >= 0; k-- {
+			res = res*x + p.Coeffs[k]
+		}
+		return res
+	}
+	// Clenshaw
+	a, b := p.Interval[0], p.Interval[1]
+	u := (2*x - a - b) / (b - a)
+	b1, b2 := 0.0, 0.0
+	for k := len(p.Coeffs) - 1; k >= 1; k-- {
+		b1, b2 = 2*u*b1-b2+p.Coeffs[k], b1
+	}
+	return u*b1 - b2 + p.Coeffs[0]
+}
+
+// indique si l'intervalle de la base de Chebyshev doit être ramené sur [-1, 1]
+func (p Polynomial) needsRescaling() bool {
+	return p.Basis == ChebyshevBasis && (p.Interval[0] != -1 || p.Interval[1] != 1)
+}
+
+// retourne l'indice du dernier coefficient non nul
+func degree(coeffs []float64) int {
+	for k := len(coeffs) - 1; k > 0; k-- {
+		if coeffs[k] != 0 {
+			return k
+		}
+	}
+	return 0
+}
+
+// retourne ceil(log2(degree + 1)), la profondeur multiplicative minimale d'un polynôme de degré <degree> >= 1
+func optimalDepth(degree int) int {
+	if degree < 1 {
+		degree = 1
+	}
+	return bits.Len(uint(degree))
+}
+
+// retourne ceil(log2(<j>)), le nombre de niveaux consommés par le calcul de T_j (ou u^j)
+func powerDepth(j int) int {
+	return bits.Len(uint(j - 1))
+}
+
+// Évaluation d'un polynôme par baby-step giant-step (Paterson-Stockmeyer) :
+// p est divisé récursivement par les giant steps T_(2^k) (ou x^(2^k)), p = q * T_(2^k) + r,
+// jusqu'à des feuilles de degré < baby, combinaisons linéaires des baby steps T_1, ..., T_(baby-1)
+// chaque sous-polynôme est évalué directement au niveau et à l'échelle attendus par son parent :
+// l'échelle de q est choisie pour qu'après le produit par T_(2^k) et le RS, elle soit celle de r,
+// ce qui évite tout réalignement, et les RS sont insérés automatiquement
+type polyEvaluator struct {
+	ckks   *CKKS
+	evk    SwitchingKey
+	basis  Basis
+	powers map[int]CT // T_j(u) (ou u^j) déjà calculés, T_j au niveau level0 - ceil(log2(j))
+	level0 int        // niveau de u
+	baby   int        // borne (exclue) sur le degré des feuilles, puissance de 2
+}
+
+// retourne un ciphertext chiffrant p(x), où x est le message de <ct>, à l'échelle de <ct>
+// le résultat est au niveau ct.L - p.Depth() : ErrLevelExhausted si <ct> n'a pas assez de niveaux
+// ErrInvalidArgument si <p> est dans la base de Chebyshev sur un intervalle vide
+func (ckks *CKKS) EvalPoly(ct CT, p Polynomial, evk SwitchingKey) (CT, error) {
+	if p.Basis == ChebyshevBasis {
+		if err := checkInterval(p.Interval[0], p.Interval[1]); err != nil {
+			return CT{}, err
+		}
+	}
+	if err := ckks.checkDegree(ct); err != nil {
+		return CT{}, err
+	}
+	if ct.L < p.Depth() {
+		return CT{}, fmt.Errorf("%w : the polynomial needs %d levels, the ciphertext has %d", ErrLevelExhausted, p.Depth(), ct.L)
+	}
+	if !ckks.isSwitchingKey(evk) {
+		return CT{}, fmt.Errorf("%w : evaluation key", ErrMissingKey)
+	}
+	return ckks.evalPoly(ct, p, evk), nil
+}
+
+// comme EvalPoly, pour un ciphertext de degré 1 ayant assez de niveaux
+func (ckks *CKKS) evalPoly(ct CT, p Polynomial, evk SwitchingKey) CT {
+
+	scale := real(ct.Scale)
+	u := ct
+	if p.needsRescaling() {
+		a, b := p.Interval[0], p.Interval[1]
+		u = ckks.multConstTo(ct, complex(2/(b-a), 0), ct.L-1, scale)
+		u = ckks.addConst(u, complex(-(a+b)/(b-a), 0))
+	}
+
+	coeffs := make([]float64, len(p.Coeffs))
+	copy(coeffs, p.Coeffs)
+	if len(coeffs) < 2 {
+		coeffs = append(coeffs, 0, 0)[:2]
+	}
+
+	depth := optimalDepth(degree(coeffs))
+	ev := polyEvaluator{
+		ckks:   ckks,
+		evk:    evk,
+		basis:  p.Basis,
+		powers: map[int]CT{1: u},
+		level0: u.L,
+		baby:   1 << ((depth + 1) / 2),
+	}
+	return ev.eval(coeffs, depth, scale)
+}
+
+// retourne T_j(u) (ou u^j), au niveau level0 - ceil(log2(j)), en le calculant si nécessaire
+// T_(2a) = 2*T_a^2 - 1 et T_(a+b) = 2*T_a*T_b - T_(a-b), où a est la plus grande puissance de 2 <= a+b
+func (ev *polyEvaluator) power(j int) CT {
+
+	if res, ok := ev.powers[j]; ok {
+		return res
+	}
+
+	ckks := ev.ckks
+	a := 1 << (bits.Len(uint(j)) - 1)
+	b := j - a
+	if b == 0 {
+		b = a / 2
+		a = a / 2
+	}
+	pa, pb := ev.power(a), ev.power(b)
+	res := ckks.mult(pa, ckks.dropToLevel(pb, pa.L), ev.evk)
+	ckks.rescale(&res)
+	if ev.basis == ChebyshevBasis {
+		res.CTScale(big.NewInt(2))
+		if a == b {
+			res = ckks.addConst(res, -1)
+		} else {
+			res = ckks.ctSub(res, ckks.multConstTo(ev.power(a-b), 1, res.L, real(res.Scale)))
+		}
+	}
+
+	ev.powers[j] = res
+	return res
+}
+
+// retourne un ciphertext chiffrant sum_k coeffs[k] T_k(u) (ou u^k), au niveau level0 - <budget> et à l'échelle <scale>
+// <budget> doit être au moins optimalDepth(degree(coeffs))
+func (ev *polyEvaluator) eval(coeffs []float64, budget int, scale float64) CT {
+
+	ckks := ev.ckks
+	deg := degree(coeffs)
+	level := ev.level0 - budget
+
+	// feuille : les T_j y sont au plus au niveau level0 - ceil(log2(deg)), la multiplication par les constantes consomme un niveau
+	if deg < 2 || (deg < ev.baby && powerDepth(deg)+1 <= budget) {
+		return ev.leaf(coeffs, level, scale)
+	}
+
+	g := 1 << (bits.Len(uint(deg)) - 1)
+	q, r := ev.divide(coeffs, g)
+	giant := ev.power(g)
+
+	// le produit q * T_g est calculé au niveau level + 1 puis divisé par q_(level+1)
+	var prod CT
+	if degree(q) == 0 {
+		prod = ckks.multConstTo(giant, complex(q[0], 0), level, scale)
+	} else {
+		qScale := scale * float64(ckks.Moduli[level+1]) / real(giant.Scale)
+		prod = ev.eval(q, budget-1, qScale)
+		prod = ckks.mult(prod, ckks.dropToLevel(giant, level+1), ev.evk)
+		ckks.rescale(&prod)
+		prod.Scale = complex(scale, 0)
+	}
+
+	if degree(r) == 0 {
+		return ckks.addConst(prod, complex(r[0], 0))
+	}
+	return ckks.add(prod, ev.eval(r, budget, scale))
+}
+
+// retourne un ciphertext chiffrant sum_k coeffs[k] T_k(u) (ou u^k) au niveau <level> et à l'échelle <scale>
+func (ev *polyEvaluator) leaf(coeffs []float64, level int, scale float64) CT {
+
+	ckks := ev.ckks
+	var res CT
+	first := true
+	for k := 1; k <= degree(coeffs); k++ {
+		if coeffs[k] == 0 {
+			continue
+		}
+		term := ckks.multConstTo(ev.power(k), complex(coeffs[k], 0), level, scale)
+		if first {
+			res, first = term, false
+		} else {
+			res = ckks.add(res, term)
+		}
+	}
+	if first { // polynôme constant : on part de 0 * u
+		res = ckks.multConstTo(ev.power(1), 0, level, scale)
+	}
+	return ckks.addConst(res, complex(coeffs[0], 0))
+}
+
+// retourne (q, r) tels que p = q * T_g + r (ou q * x^g + r), avec deg(r) < g, pour deg(p) < 2g
+// dans la base de Chebyshev, T_j * T_g = (T_(g+j) + T_(g-j)) / 2
+func (ev *polyEvaluator) divide(coeffs []float64, g int) ([]float64, []float64) {
+
+	deg := degree(coeffs)
+	q := make([]float64, deg-g+1)
+	r := make([]float64, g)
+	copy(r, coeffs[:g])
+	copy(q, coeffs[g:deg+1])
+
+	if ev.basis == ChebyshevBasis {
+		for j := 1; j < len(q); j++ {
+			r[g-j] -= q[j]
+			q[j] *= 2
+		}
+	}
+	return q, r
+}
@@ -0,0 +1,136 @@
+package ckks
+
+import (
+	"fmt"
+	"math/big"
+
+	"kazat.ch/lbcrypto/encoder"
+	"kazat.ch/lbcrypto/poly"
+)
+
+// Déchiffrement à seuil (t-out-of-n) par partage de Shamir de la sk
+// Le détenteur de la sk (1, s) de SKeyGen tire un polynôme f(X) = s + c_1*X + ... + c_{t-1}*X^{t-1},
+// les c_k étant des polynômes uniformes modulo Q * P (les premiers de la base étant des corps, le partage se fait
+// premier par premier), et donne à la partie i la part s_i = f(i), pour i = 1, ..., n.
+// Un groupe d'au moins t parties reconstruit s = sum_i λ_i * s_i, où les λ_i sont les coefficients de Lagrange
+// du groupe en 0 : chaque partie multiplie sa part par son λ_i (LagrangeShare) avant d'ajouter son bruit de lissage,
+// ce qui ramène le groupe au cas n-out-of-n (DecryptionShare, PublicKeySwitchShare, ...).
+// Multiplier les parts de déchiffrement par les λ_i après coup multiplierait aussi leur bruit par des entiers
+// de la taille de Q
+
+// Partie d'un groupe de déchiffrement : l'indice et le seuil de sa part, publics et échangés au sein du groupe
+type ThresholdParty struct {
+	Index     int // point d'évaluation x_i = Index du polynôme de partage, entre 1 et n
+	Threshold int // nombre t de parts nécessaires au déchiffrement
+}
+
+// Part de Shamir de la sk, détenue par la partie Index
+type ThresholdShare struct {
+	ThresholdParty
+	Sk SecretKey // (1, f(x_i)), définie modulo Q * P comme la sk partagée
+}
+
+// retourne les parts de Shamir de la sk <sk> pour <n> parties, <t> d'entre elles étant nécessaires au déchiffrement
+// ErrInvalidArgument si <t> n'est pas entre 1 et <n>, si <n> dépasse 2^16, ou si un premier de Q * P est au plus <n>
+// (les indices 1, ..., n doivent rester distincts et leurs différences inversibles modulo chaque premier)
+func (ckks *CKKS) ShareSecretKey(sk [2]poly.RNSPoly, t, n int) ([]ThresholdShare, error) {
+	if t < 1 || t > n || n > 1<<16 {
+		return nil, fmt.Errorf("%w : threshold %d for %d parties (at most 2^16)", ErrInvalidArgument, t, n)
+	}
+	for _, q := range ckks.modulusQP() {
+		if q <= uint64(n) {
+			return nil, fmt.Errorf("%w : the prime %d cannot share a key between %d parties", ErrInvalidArgument, q, n)
+		}
+	}
+
+	// coefficients de f : c_0 = s, puis c_1, ..., c_{t-1} uniformes
+	coefs := make([]poly.RNSPoly, t)
+	coefs[0] = sk[1]
+	for k := 1; k < t; k++ {
+		coefs[k], _ = ckks.uniformNTT(ckks.modulusQP())
+	}
+
+	shares := make([]ThresholdShare, n)
+	for i := range shares {
+		// f(x) par la méthode de Horner
+		x := big.NewInt(int64(i + 1))
+		f := coefs[t-1]
+		for k := t - 2; k >= 0; k-- {
+			f = poly.AddRNS(poly.ScaleRNS(f, x), coefs[k])
+		}
+		shares[i] = ThresholdShare{ThresholdParty: ThresholdParty{Index: i + 1, Threshold: t}, Sk: SecretKey{sk[0], f}}
+	}
+	return shares, nil
+}
+
+// retourne le coefficient de Lagrange en 0 du point <x> pour les points <participants>, modulo <mod> :
+// le produit des x_j / (x_j - x) pour x_j != x
+// ErrInvalidArgument si le produit des x_j - x n'est pas inversible modulo <mod>
+func lagrangeCoefficient(x int, participants []ThresholdParty, mod *big.Int) (*big.Int, error) {
+	num, den := big.NewInt(1), big.NewInt(1)
+	for _, party := range participants {
+		if xj := party.Index; xj != x {
+			num.Mul(num, big.NewInt(int64(xj)))
+			den.Mul(den, big.NewInt(int64(xj-x)))
+		}
+	}
+	den.Mod(den, mod)
+	if den.ModInverse(den, mod) == nil {
+		return nil, fmt.Errorf("%w : the Lagrange coefficient of party %d is not defined modulo the key modulus", ErrInvalidArgument, x)
+	}
+	return num.Mul(num, den).Mod(num, mod), nil
+}
+
+// retourne la sk (1, λ_i * s_i) de la partie de part <share> au sein du groupe <participants> (les indices et seuils
+// des parts du groupe) : la somme de ces sk sur le groupe est la sk partagée, de sorte que les protocoles à n parties
+// (DecryptionShare, PublicKeySwitchShare, ...) s'appliquent au groupe
+// ErrInvalidArgument si le groupe compte moins de share.Threshold parties distinctes, ne contient pas <share>,
+// si une partie déclare un autre seuil, ou si un coefficient de Lagrange n'est pas défini (voir lagrangeCoefficient)
+func (ckks *CKKS) LagrangeShare(share ThresholdShare, participants []ThresholdParty) (SecretKey, error) {
+	seen := map[int]bool{}
+	for _, party := range participants {
+		if x := party.Index; x < 1 || x > 1<<16 || seen[x] {
+			return SecretKey{}, fmt.Errorf("%w : invalid or repeated party index %d", ErrInvalidArgument, x)
+		}
+		if party.Threshold != share.Threshold {
+			return SecretKey{}, fmt.Errorf("%w : party %d has a threshold of %d instead of %d", ErrInvalidArgument, party.Index, party.Threshold, share.Threshold)
+		}
+		seen[party.Index] = true
+	}
+	if !seen[share.Index] {
+		return SecretKey{}, fmt.Errorf("%w : party %d is not among the participants", ErrInvalidArgument, share.Index)
+	}
+	if len(participants) < share.Threshold {
+		return SecretKey{}, fmt.Errorf("%w : %d participants for a threshold of %d", ErrInvalidArgument, len(participants), share.Threshold)
+	}
+
+	mod := poly.ProdModuli(share.Sk[1].Moduli)
+	lambda, err := lagrangeCoefficient(share.Index, participants, mod)
+	if err != nil {
+		return SecretKey{}, err
+	}
+	return SecretKey{share.Sk[0], poly.ScaleRNS(share.Sk[1], lambda)}, nil
+}
+
+// retourne la part de déchiffrement du ciphertext <ct> de la partie de part <share> au sein du groupe <participants> :
+// la part de DecryptionShare pour sa sk de LagrangeShare
+// les parts du groupe se combinent avec ThresholdDecrypt
+// erreurs de LagrangeShare et de DecryptionShare
+func (ckks *CKKS) ThresholdDecryptionShare(ct CT, share ThresholdShare, participants []ThresholdParty, smudgingStd float64) (poly.RNSPoly, error) {
+	sk, err := ckks.LagrangeShare(share, participants)
+	if err != nil {
+		return poly.RNSPoly{}, err
+	}
+	return ckks.DecryptionShare(ct, sk, smudgingStd)
+}
+
+// retourne le plaintext du ciphertext <ct> à partir des parts de déchiffrement <shares> de tout un groupe
+// (ThresholdDecryptionShare) : B + sum_i (A * λ_i * s_i + e_i), que encoder.Decode accepte
+// erreurs de AggregateDecryptionShares et de CombineDecryption
+func (ckks *CKKS) ThresholdDecrypt(ct CT, shares ...poly.RNSPoly) (encoder.PT, error) {
+	agg, err := ckks.AggregateDecryptionShares(shares...)
+	if err != nil {
+		return encoder.PT{}, err
+	}
+	return ckks.CombineDecryption(ct, agg)
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
+	"math/cmplx"
 	"math/rand"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,7 +38,7 @@ var boundForGradeEntries = 6.0   // like boundForVectorEntries, but for vectors
 
 var NN = 64 // used below for the polynomial modulus x^NN + 1
 
-var q0_nb_bits = 1000  // used to get q_L = q_0 * Delta^L
+var q0_nb_bits = 60    // used to get q_L = q_0 * Delta^L (q_0 is a prime, at most 61 bits)
 var delta_nb_bits = 20 // precision and modulus related param (greater means more precision and bigger q_L modulus)
 var nb_levels = 10     // number of levels
 
@@ -132,6 +141,39 @@ func compare(a, b cMat.CMat) float64 {
 	return a.MaxNorm()
 }
 
+// returns the plaintext encoding <v>, panics if the dimension of <v> does not match <enc>
+func mustEncode(enc encoder.Encoder, v *cMat.CMat) encoder.PT {
+	pt, err := enc.Encode(v)
+	if err != nil {
+		panic(err)
+	}
+	return pt
+}
+
+// returns <ckks1>, panics if <err> is not nil : mustCKKS(ckks.NewCKKS(...))
+func mustCKKS(ckks1 ckks.CKKS, err error) ckks.CKKS {
+	if err != nil {
+		panic(err)
+	}
+	return ckks1
+}
+
+// returns <enc>, panics if <err> is not nil : mustEncoder(encoder.NewEncoder(N, scale))
+func mustEncoder(enc encoder.Encoder, err error) encoder.Encoder {
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// returns <ct>, panics if <err> is not nil : mustCT(ckks.CTAdd(ct1, ct2))
+func mustCT(ct ckks.CT, err error) ckks.CT {
+	if err != nil {
+		panic(err)
+	}
+	return ct
+}
+
 // tests the random.DG function
 func _TestDG(t *testing.T) {
 	N := 10000
@@ -140,6 +182,29 @@ func _TestDG(t *testing.T) {
 	fmt.Println(random.DG(N, s2, max))
 }
 
+// tests random.DGFrom : the samples only depend on the stream they are read from
+func TestDGFrom(t *testing.T) {
+	N := 4096
+	max := big.NewInt(1 << 62)
+	seed := random.NewSeed()
+	a := random.DGFrom(N, 1<<16, max, random.NewPRNG(seed))
+	b := random.DGFrom(N, 1<<16, max, random.NewPRNG(seed))
+	c := random.DGFrom(N, 1<<16, max, random.NewPRNG(random.NewSeed()))
+
+	sameAB, sameAC, sum2 := true, true, 0.0
+	for i := range a {
+		sameAB = sameAB && a[i].Cmp(b[i]) == 0
+		sameAC = sameAC && a[i].Cmp(c[i]) == 0
+		x := float64(a[i].Int64())
+		sum2 += x * x
+	}
+	std := math.Sqrt(sum2 / float64(N))
+	fmt.Printf("DGFrom : standard deviation %f (expected 256) \n", std)
+	if !sameAB || sameAC || math.Abs(std-256) > 20 {
+		t.Fail()
+	}
+}
+
 // tests the copy of a poly.Poly returning a copy of a polynomial
 func _TestPolyCopy(t *testing.T) {
 	fmt.Println("Testing the polynomial division")
@@ -194,7 +259,7 @@ func _TestPolyDiv(t *testing.T) {
 	v := poly.NewPoly(coefsv)
 
 	fmt.Println("u and v befor division: ", u, v)
-	q, r := poly.PolyDiv(u, v)
+	q, r, _ := poly.PolyDiv(u, v)
 	fmt.Println("u and v after division: ", u, v)
 
 	fmt.Println("q and r: ", q, r)
@@ -224,11 +289,11 @@ func _TestScaleDiv(t *testing.T) {
 // tests the encoder.Encode function encoding a complex vector into a polynomial
 func _TestEncoding(t *testing.T) {
 
-	enc := encoder.NewEncoder(NN, baseScale)
+	enc := mustEncoder(encoder.NewEncoder(NN, baseScale))
 	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
 	va.PPrint()
 
-	ya := enc.Encode(&va)
+	ya := mustEncode(enc, &va)
 	fmt.Println("Polynomial encoding of the vector a:")
 	fmt.Println(ya)
 
@@ -245,14 +310,14 @@ func _TestEncodingBis(t *testing.T) {
 	bound := 100000.0
 	baseScale = 1
 
-	enc := encoder.NewEncoder(NN, baseScale)
+	enc := mustEncoder(encoder.NewEncoder(NN, baseScale))
 
 	maxErr := 0.0
 
 	for i := 0; i <= 100; i++ {
 		data := randComplexVectBoundedNorm(NN/2, bound)
 		va := cMat.NewCMat(NN/2, 1, data)
-		ya := enc.Encode(&va)
+		ya := mustEncode(enc, &va)
 		fmt.Println(ya)
 		za := enc.Decode(ya)
 		diff := compare(va, za)
@@ -271,17 +336,14 @@ func _TestEncodingBis(t *testing.T) {
 func _TestEncrypt(t *testing.T) {
 	fmt.Println("Testing the encryption")
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
-	ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
 
 	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
 
 	//********************************
 	fmt.Println("ENCODING")
-	enc := encoder.NewEncoder(ckks.N, baseScale)
-	pta := enc.Encode(&va)
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
+	pta := mustEncode(enc, &va)
 
 	//********************************
 	fmt.Println("GENERATING KEYS")
@@ -300,10 +362,7 @@ func _TestEncrypt(t *testing.T) {
 func TestEncryptDecrypt(t *testing.T) {
 	fmt.Println("TESTING THE ENCRYPTION - DECRYPTION")
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
-	ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
 
 	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
 
@@ -312,8 +371,8 @@ func TestEncryptDecrypt(t *testing.T) {
 
 	//********************************
 	//fmt.Println("ENCODING :")
-	enc := encoder.NewEncoder(ckks.N, baseScale)
-	pta := enc.Encode(&va)
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
+	pta := mustEncode(enc, &va)
 
 	//********************************
 	//fmt.Println("KEYS GENERATION :")
@@ -348,10 +407,7 @@ func TestHomomAdd(t *testing.T) {
 	for i := 0; i < 1; i = i + 1 {
 		fmt.Println("TESTING HOMOMORPHISM ON +")
 
-		Q := new(big.Int)
-		Q.SetString(QL, 2)
-		P := Q
-		ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
+		ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
 
 		//********************************
 		v1 := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
@@ -365,10 +421,10 @@ func TestHomomAdd(t *testing.T) {
 
 		//********************************
 		//fmt.Println("ENCODING")
-		enc := encoder.NewEncoder(ckks.N, baseScale)
-		pt1 := enc.Encode(&v1)
-		pt2 := enc.Encode(&v2)
-		//fmt.Println("pts :", enc.Encode(&vs))
+		enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
+		pt1 := mustEncode(enc, &v1)
+		pt2 := mustEncode(enc, &v2)
+		//fmt.Println("pts :", mustEncode(enc, &vs))
 
 		//********************************
 		//fmt.Println("GENERAING KEYS")
@@ -385,7 +441,7 @@ func TestHomomAdd(t *testing.T) {
 		//fmt.Println("ct1 :", ct1)
 		//fmt.Println("ct2 :", ct2)
 
-		cts := ckks.CTAdd(ct1, ct2)
+		cts := mustCT(ckks.CTAdd(ct1, ct2))
 		//fmt.Println("cts :", cts)
 
 		//********************************
@@ -411,10 +467,7 @@ func TestHomomAdd(t *testing.T) {
 func _TestKeyGen(t *testing.T) {
 	fmt.Println("Testing the key generation")
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
-	ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
 
 	//********************************
 	fmt.Println("GENERATING KEYS")
@@ -435,11 +488,8 @@ func TestHomomMult(t *testing.T) {
 	rand.Seed(time.Now().UnixNano())
 	fmt.Println("TESTING HOMOMORPHISM ON *")
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
-	ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
-	enc := encoder.NewEncoder(ckks.N, baseScale)
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
 
 	//********************************
 	v1 := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, float64(boundForVectorEntries)))
@@ -453,12 +503,12 @@ func TestHomomMult(t *testing.T) {
 
 	//********************************
 	//fmt.Println("ENCODING")
-	pt1 := enc.Encode(&v1)
-	pt2 := enc.Encode(&v2)
+	pt1 := mustEncode(enc, &v1)
+	pt2 := mustEncode(enc, &v2)
 	//fmt.Println("pt1 :", pt1)
 	//fmt.Println("pt2 :", pt2)
 
-	//fmt.Println("pt of prod :", enc.Encode(&vp))
+	//fmt.Println("pt of prod :", mustEncode(enc, &vp))
 
 	//********************************
 	//fmt.Println("GENERAING KEYS")
@@ -473,7 +523,7 @@ func TestHomomMult(t *testing.T) {
 
 	//********************************
 	//fmt.Println("PRODUCT OF CYPHERTEXTS")
-	ctp := ckks.CTMult(ct1, ct2, evk)
+	ctp := mustCT(ckks.CTMult(ct1, ct2, evk))
 	//fmt.Println("ctp :", ctp)
 	//fmt.Println("ctp scale :", ctp.Scale)
 	//********************************
@@ -496,7 +546,7 @@ func TestHomomMult(t *testing.T) {
 func _TestConstant(t *testing.T) {
 	k := 4.0
 
-	enc := encoder.NewEncoder(NN, baseScale)
+	enc := mustEncoder(encoder.NewEncoder(NN, baseScale))
 
 	pt := enc.ConstToPT(k)
 	fmt.Println("Encoding of constant vector (k=4) with baseScale = 64")
@@ -509,11 +559,8 @@ func TestHomScaling(t *testing.T) {
 
 	k := -10
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
-	ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
-	enc := encoder.NewEncoder(ckks.N, baseScale)
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
 
 	//********************************
 	v := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
@@ -532,7 +579,7 @@ func TestHomScaling(t *testing.T) {
 
 	//********************************
 	//fmt.Println("ENCODING")
-	pt := enc.Encode(&v)
+	pt := mustEncode(enc, &v)
 
 	//********************************
 	//fmt.Println("ENCRYPTING")
@@ -570,12 +617,9 @@ func TestCTIncScale(t *testing.T) {
 	k := new(big.Int)
 	k.SetString("10", 2)
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
 
-	ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
-	enc := encoder.NewEncoder(ckks.N, baseScale)
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
 
 	//********************************
 	v := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
@@ -589,7 +633,7 @@ func TestCTIncScale(t *testing.T) {
 
 	//********************************
 	//fmt.Println("ENCODING")
-	pt := enc.Encode(&v)
+	pt := mustEncode(enc, &v)
 
 	//********************************
 	//fmt.Println("ENCRYPTING")
@@ -628,12 +672,9 @@ func TestMean(t *testing.T) {
 	nbCourses := 10
 
 	N := 2 * nbStudents
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
 
-	ckks1 := ckks.NewCKKS(Q, P, N, h, nb_levels, s2)
-	enc := encoder.NewEncoder(ckks1.N, baseScale)
+	ckks1 := mustCKKS(ckks.NewCKKS(N, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
 
 	//********************************
 
@@ -659,13 +700,12 @@ func TestMean(t *testing.T) {
 
 	pt := make([]encoder.PT, nbCourses)
 	for i := 0; i < nbCourses; i++ {
-		pt[i] = enc.Encode(&vectors[i])
+		pt[i] = mustEncode(enc, &vectors[i])
 	}
 
 	//********************************
 	sk := ckks1.SKeyGen()
 	pk := ckks1.PKeyGen(sk)
-	evk := ckks1.EvKeyGen(sk)
 	//********************************
 	ct := make([]ckks.CT, nbCourses)
 
@@ -674,8 +714,8 @@ func TestMean(t *testing.T) {
 	}
 	//********************************
 	//fmt.Println("PERFORMING COMPUTATIONS IN CT SPACE")
-	ctMean := ckks1.Mean(ct, pk, evk) //, deltaBigInt)
-	ckks1.RS(&ctMean, deltaBigInt)
+	ctMean := mustCT(ckks1.Mean(ct)) //, deltaBigInt)
+	ckks1.RS(&ctMean)
 	//fmt.Println("ctmean scale :", ctMean.Scale)
 	//********************************
 	//fmt.Println("DECRYPTING - DECODING")
@@ -698,9 +738,6 @@ func TestMean(t *testing.T) {
 func TestRS(t *testing.T) {
 	fmt.Println("TESTING RS")
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
 
 	//floatScale := math.Pow(2, 10)
 	//delta := complex(floatScale, 0)
@@ -709,8 +746,8 @@ func TestRS(t *testing.T) {
 	//Q.Mul(Q, bigBaseScale)
 	//Q.Mul(Q, bigBaseScale)
 
-	ckks := ckks.NewCKKS(Q, P, NN, h, nb_levels, s2)
-	enc := encoder.NewEncoder(ckks.N, baseScale)
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
 
 	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
 
@@ -718,7 +755,7 @@ func TestRS(t *testing.T) {
 
 	//********************************
 	//fmt.Println("ENCODING")
-	pt := enc.Encode(&va)
+	pt := mustEncode(enc, &va)
 	//fmt.Println("pt: ", pt)
 
 	//********************************
@@ -736,7 +773,7 @@ func TestRS(t *testing.T) {
 	ct.CTIncScale(deltaBigInt)
 	ct.CTIncScale(deltaBigInt)
 
-	ckks.RS(&ct, deltaBigInt)
+	ckks.RS(&ct)
 
 	//fmt.Println("ct :", ct)
 
@@ -765,12 +802,9 @@ func TestVar(t *testing.T) {
 	nbStudents := NN
 	nbCourses := 50
 
-	Q := new(big.Int)
-	Q.SetString(QL, 2)
-	P := Q
 	N := 2 * nbStudents
 
-	ckks1 := ckks.NewCKKS(Q, P, N, h, nb_levels, s2)
+	ckks1 := mustCKKS(ckks.NewCKKS(N, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
 
 	//********************************
 
@@ -789,10 +823,10 @@ func TestVar(t *testing.T) {
 	//********************************
 	//fmt.Println("ENCODING-ENCODING DATA")
 
-	enc := encoder.NewEncoder(ckks1.N, baseScale)
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
 	pts := make([]encoder.PT, nbCourses)
 	for i := 0; i < nbCourses; i++ {
-		pts[i] = enc.Encode(&vectors[i])
+		pts[i] = mustEncode(enc, &vectors[i])
 	}
 
 	//********************************
@@ -808,7 +842,7 @@ func TestVar(t *testing.T) {
 	//********************************
 
 	//fmt.Println("PERFORMING COMPUTATIONS IN CT SPACE")
-	ctVar := ckks1.Var(cts, pk, evk, deltaBigInt)
+	ctVar := mustCT(ckks1.Var(cts, evk))
 
 	//********************************
 	//fmt.Println("DECRYPTING - DECODING")
@@ -840,7 +874,7 @@ func TestVar(t *testing.T) {
 	}
 	sig2.Scale(1 / complex(float64(nbCourses), 0))
 
-	//ptcheck := enc.Encode(&sig2)
+	//ptcheck := mustEncode(enc, &sig2)
 	//fmt.Println("encoding of tru var: ", ptcheck)
 	//fmt.Println("Var computed on original data :")
 	//sig2.PPrint()
@@ -853,3 +887,1903 @@ func TestVar(t *testing.T) {
 		t.Fail()
 	}
 }
+
+// tests the RNS representation : poly.ToRNS - ToPoly round trip and poly.MultModRNS against poly.MultMod
+func TestRNS(t *testing.T) {
+	fmt.Println("TESTING RNS REPRESENTATION")
+
+	N := 16
+	moduli, err := poly.GeneratePrimes(40, N, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Q := poly.ProdModuli(moduli)
+	halfQ := new(big.Int).Rsh(Q, 1)
+
+	// centered random polynomials modulo Q
+	u := random.RandomPol(N, Q)
+	v := random.RandomPol(N, Q)
+	u.TakeCoefMod(Q)
+	v.TakeCoefMod(Q)
+
+	ru := poly.ToRNS(u, N, moduli)
+	back := ru.ToPoly()
+	for i := range u.Coefs {
+		if back.Coefs[i].Cmp(u.Coefs[i]) != 0 {
+			fmt.Println("wrong coef after round trip :", i, u.Coefs[i], back.Coefs[i])
+			t.Fail()
+		}
+	}
+
+	cyclo := poly.ZeroPoly(N)
+	cyclo.Coefs[0].SetInt64(1)
+	cyclo.Coefs[N].SetInt64(1)
+	expected, err := poly.MultMod(u, v, cyclo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected.TakeCoefMod(Q)
+
+	rv := poly.ToRNS(v, N, moduli)
+	prod := poly.MultModRNS(ru, rv)
+	got := prod.ToPoly()
+	for i := range expected.Coefs {
+		diff := new(big.Int).Sub(got.Coefs[i], expected.Coefs[i])
+		if diff.Mod(diff, Q).Sign() != 0 || new(big.Int).Abs(got.Coefs[i]).Cmp(halfQ) == 1 {
+			fmt.Println("wrong coef in product :", i, expected.Coefs[i], got.Coefs[i])
+			t.Fail()
+		}
+	}
+}
+
+// tests the NTT based ckks.CTMult for a large ring (N = 2^12) on monomial plaintexts a*X^i and b*X^j
+// whose product is a*b*X^(i+j) (up to the sign given by X^N = -1)
+func TestNTTLargeN(t *testing.T) {
+	fmt.Println("TESTING NTT MULTIPLICATION FOR N = 2^12")
+
+	N := 1 << 12
+	ckks1 := mustCKKS(ckks.NewCKKS(N, 64, 2, 60, 40, s2))
+	delta := math.Pow(2, 30)
+
+	monomial := func(c float64, i int) encoder.PT {
+		pol := poly.ZeroPoly(N - 1)
+		pol.Coefs[i].SetInt64(int64(c * delta))
+		return encoder.NewPT(pol, complex(delta, 0))
+	}
+	a, b := 3.0, -5.0
+	i, j := 1000, 3500
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	ct1 := ckks1.Encrypt(monomial(a, i), pk)
+	ct2 := ckks1.Encrypt(monomial(b, j), pk)
+	ctp := mustCT(ckks1.CTMult(ct1, ct2, evk))
+	ckks1.RS(&ctp)
+
+	pt := ckks1.Decrypt(ctp, sk)
+	scale := real(pt.Scale)
+	err := 0.0
+	for k, c := range pt.Pol.Coefs {
+		expected := 0.0
+		if k == (i+j)%N {
+			expected = -a * b // i + j >= N
+		}
+		coef, _ := new(big.Float).SetInt(c).Float64()
+		err = math.Max(err, math.Abs(coef/scale-expected))
+	}
+	fmt.Printf("max norm of errors : %f \n", err)
+	if err > tolerance {
+		t.Fail()
+	}
+}
+
+// returns the vector <v> cyclically shifted by <k> positions to the left
+func rotate(v cMat.CMat, k int) cMat.CMat {
+	data := v.GetData()
+	n := len(data)
+	rot := make([]complex128, n)
+	for i := range rot {
+		rot[i] = data[((i+k)%n+n)%n]
+	}
+	return cMat.NewCMat(n, 1, rot)
+}
+
+// tests the ckks.CTRotate function shifting the slots of a ciphertext, with a dedicated key
+// and with a rotation decomposed in powers of 2
+func TestRotation(t *testing.T) {
+	fmt.Println("TESTING ROTATIONS")
+
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+
+	sk := ckks.SKeyGen()
+	pk := ckks.PKeyGen(sk)
+	keys := ckks.RotationKeysGen(sk)
+	keys[3] = ckks.RotationKeyGen(sk, 3)
+
+	ct := ckks.Encrypt(mustEncode(enc, &va), pk)
+
+	for _, k := range []int{3, 5, -1} {
+		ctRot := mustCT(ckks.CTRotate(ct, k, keys))
+		vect := enc.Decode(ckks.Decrypt(ctRot, sk))
+
+		err := compare(vect, rotate(va, k))
+		fmt.Printf("rotation by %d, max norm of errors : %f \n", k, err)
+		if err > tolerance {
+			t.Fail()
+		}
+	}
+}
+
+// tests the ckks.CTConjugate function, and the extraction of the real part (z + conj(z))/2
+func TestConjugation(t *testing.T) {
+	fmt.Println("TESTING CONJUGATION")
+
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	vConj := va.Copy()
+	vConj.Conjugate()
+	vRe := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	for i, z := range va.GetData() {
+		vRe.GetData()[i] = complex(real(z), 0)
+	}
+
+	sk := ckks.SKeyGen()
+	pk := ckks.PKeyGen(sk)
+	cjk := ckks.ConjugationKeyGen(sk)
+
+	ct := ckks.Encrypt(mustEncode(enc, &va), pk)
+	ctConj := mustCT(ckks.CTConjugate(ct, cjk))
+
+	// (z + conj(z))/2 : the division by 2 is done by doubling the scale
+	ctRe := mustCT(ckks.CTAdd(ct, ctConj))
+	ctRe.Scale *= 2
+
+	err := compare(enc.Decode(ckks.Decrypt(ctConj, sk)), vConj)
+	errRe := compare(enc.Decode(ckks.Decrypt(ctRe, sk)), vRe)
+	fmt.Printf("max norm of errors : %f (conjugate), %f (real part) \n", err, errRe)
+	if err > tolerance || errRe > tolerance {
+		t.Fail()
+	}
+}
+
+// tests the plaintext - ciphertext operations ckks.CTAddPlain, ckks.CTSubPlain, ckks.CTMultPlain,
+// and their scalar variants ckks.CTAddConst and ckks.CTMultConst
+func TestPlainOps(t *testing.T) {
+	fmt.Println("TESTING PLAINTEXT - CIPHERTEXT OPERATIONS")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+
+	v1 := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	v2 := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	c := randComplex(boundForVectorEntries)
+
+	sum := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	sum.Add(&v1, &v2)
+	diff := v2.Copy()
+	diff.Scale(-1)
+	diff.Add(&v1, &diff)
+	prod := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	prod.CoefWiseProd(&v1, &v2)
+	sumConst := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	prodConst := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	for i, z := range v1.GetData() {
+		sumConst.GetData()[i] = z + c
+		prodConst.GetData()[i] = z * c
+	}
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+
+	ct := ckks1.Encrypt(mustEncode(enc, &v1), pk)
+	pt := mustEncode(enc, &v2)
+
+	ctProd := mustCT(ckks1.CTMultPlain(ct, pt))
+	ckks1.RS(&ctProd)
+	ctProdConst := mustCT(ckks1.CTMultConst(ct, c))
+	ckks1.RS(&ctProdConst)
+
+	results := []ckks.CT{mustCT(ckks1.CTAddPlain(ct, pt)), mustCT(ckks1.CTSubPlain(ct, pt)), ctProd, mustCT(ckks1.CTAddConst(ct, c)), ctProdConst}
+	expected := []cMat.CMat{sum, diff, prod, sumConst, prodConst}
+	for i := range results {
+		err := compare(enc.Decode(ckks1.Decrypt(results[i], sk)), expected[i])
+		fmt.Printf("max norm of errors : %f \n", err)
+		if err > tolerance {
+			t.Fail()
+		}
+	}
+}
+
+// tests a sum of products of ciphertexts computed with ckks.CTMultNoRelin and a single ckks.Relinearize
+func TestRelinearize(t *testing.T) {
+	fmt.Println("TESTING SUM OF PRODUCTS WITH ONE RELINEARIZATION")
+
+	nbTerms := 4
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	expected := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	var acc ckks.CT
+	for i := 0; i < nbTerms; i++ {
+		v := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+		w := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+		prod := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+		prod.CoefWiseProd(&v, &w)
+		expected.Add(&expected, &prod)
+
+		ctProd := mustCT(ckks1.CTMultNoRelin(ckks1.Encrypt(mustEncode(enc, &v), pk), ckks1.Encrypt(mustEncode(enc, &w), pk)))
+		if i == 0 {
+			acc = ctProd
+		} else {
+			acc = mustCT(ckks1.CTAdd(acc, ctProd))
+		}
+	}
+
+	// a degree 2 ciphertext can be decrypted as well (compare modifies its second argument)
+	errDeg2 := compare(enc.Decode(ckks1.Decrypt(acc, sk)), expected.Copy())
+
+	acc = mustCT(ckks1.Relinearize(acc, evk))
+	ckks1.RS(&acc)
+	err := compare(enc.Decode(ckks1.Decrypt(acc, sk)), expected)
+	fmt.Printf("max norm of errors : %f (degree 2), %f (relinearized) \n", errDeg2, err)
+	if acc.Degree() != 1 || errDeg2 > tolerance || err > tolerance {
+		t.Fail()
+	}
+}
+
+// tests plaintext and constant operations on a degree 2 ciphertext (product computed with ckks.CTMultNoRelin)
+func TestPlainOpsDegree2(t *testing.T) {
+	fmt.Println("TESTING PLAINTEXT OPERATIONS ON A NON RELINEARIZED PRODUCT")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	encProd := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale*baseScale)) // à l'échelle du produit
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	v1 := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	v2 := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	v3 := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	c := randComplex(boundForVectorEntries)
+
+	prod := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	prod.CoefWiseProd(&v1, &v2)
+	sum := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	sum.Add(&prod, &v3)
+	diff := v3.Copy()
+	diff.Scale(-1)
+	diff.Add(&prod, &diff)
+	sumConst := prod.Copy()
+	for i := range sumConst.GetData() {
+		sumConst.GetData()[i] += c
+	}
+
+	ctProd := mustCT(ckks1.CTMultNoRelin(ckks1.Encrypt(mustEncode(enc, &v1), pk), ckks1.Encrypt(mustEncode(enc, &v2), pk)))
+	pt := mustEncode(encProd, &v3)
+
+	results := []ckks.CT{mustCT(ckks1.CTAddPlain(ctProd, pt)), mustCT(ckks1.CTSubPlain(ctProd, pt)), mustCT(ckks1.CTAddConst(ctProd, c))}
+	expected := []cMat.CMat{sum, diff, sumConst}
+	for i := range results {
+		if results[i].Degree() != 2 {
+			t.Errorf("result %d : degree %d instead of 2", i, results[i].Degree())
+			continue
+		}
+		// déchiffré tel quel, puis après relinéarisation
+		errDeg2 := compare(enc.Decode(ckks1.Decrypt(results[i], sk)), expected[i].Copy())
+		ct := mustCT(ckks1.Relinearize(results[i], evk))
+		ckks1.RS(&ct)
+		err := compare(enc.Decode(ckks1.Decrypt(ct, sk)), expected[i])
+		fmt.Printf("max norm of errors : %f (degree 2), %f (relinearized) \n", errDeg2, err)
+		if errDeg2 > tolerance || err > tolerance {
+			t.Fail()
+		}
+	}
+}
+
+func TestHybridKeySwitch(t *testing.T) {
+	fmt.Println("TESTING MULTIPLICATION AND ROTATION WITH HYBRID KEY SWITCHING")
+
+	for _, dnum := range []int{1, 2, 3, nb_levels + 1} {
+		ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, dnum, s2))
+		enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+
+		sk := ckks1.SKeyGen()
+		pk := ckks1.PKeyGen(sk)
+		evk := ckks1.EvKeyGen(sk)
+		rotKeys := ckks.RotationKeys{1: ckks1.RotationKeyGen(sk, 1)}
+
+		v := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+		w := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+		expected := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+		expected.CoefWiseProd(&v, &w)
+
+		ct := mustCT(ckks1.CTMult(ckks1.Encrypt(mustEncode(enc, &v), pk), ckks1.Encrypt(mustEncode(enc, &w), pk), evk))
+		ckks1.RS(&ct)
+		err := compare(enc.Decode(ckks1.Decrypt(ct, sk)), expected.Copy())
+
+		ctRot := mustCT(ckks1.CTRotate(ct, 1, rotKeys))
+		errRot := compare(enc.Decode(ckks1.Decrypt(ctRot, sk)), rotate(expected, 1))
+
+		info := ckks1.KeySwitchingInfo()
+		fmt.Printf("dnum = %d : log P = %d, log QP = %d, key size = %d bytes, noise std = %f, errors : %f (mult), %f (rotation) \n",
+			info.Dnum, info.LogP, info.LogQP, info.KeySize, info.NoiseStd, err, errRot)
+		if len(evk) != info.Dnum || err > tolerance || errRot > tolerance {
+			t.Fail()
+		}
+	}
+}
+
+func TestKeySwitch(t *testing.T) {
+	fmt.Println("TESTING KEY SWITCHING BETWEEN TWO SECRET KEYS")
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, 2, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+
+	skOld := ckks1.SKeyGen()
+	pkOld := ckks1.PKeyGen(skOld)
+	skNew := ckks1.SKeyGen()
+	swk := ckks1.SwitchingKeyGen(skOld, skNew)
+
+	v := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	ct := ckks1.Encrypt(mustEncode(enc, &v), pkOld)
+
+	ctNew := mustCT(ckks1.KeySwitch(ct, swk))
+	err := compare(enc.Decode(ckks1.Decrypt(ctNew, skNew)), v.Copy())
+	errOld := compare(enc.Decode(ckks1.Decrypt(ctNew, skOld)), v)
+	fmt.Printf("max norm of errors : %f (new key), %f (old key) \n", err, errOld)
+	if err > tolerance || errOld < 1 {
+		t.Fail()
+	}
+}
+
+func TestBootstrapping(t *testing.T) {
+	fmt.Println("TESTING BOOTSTRAPPING (TOY PARAMETERS)")
+
+	// N = 64 is not secure, it only makes the test fast
+	params := ckks.DefaultBootstrappingParameters()
+	levels := params.Depth() + 2
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, 16, levels, 60, 45, 3, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, 45), 0)))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	keys := ckks1.BootstrappingKeyGen(sk)
+	// only the baby-step and giant-step rotations of the DFT matrices get a key
+	fmt.Printf("%d rotation keys for %d slots \n", len(keys.Rtks), NN/2)
+	if len(keys.Rtks) >= NN/2-1 {
+		t.Error("a rotation key is generated for every shift")
+	}
+
+	v := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, 1))
+	ct := ckks1.Encrypt(mustEncode(enc, &v), pk)
+	// exhausts the levels : each multiplication by 1 consumes a level and keeps the scale
+	for ct.L > 0 {
+		ct = mustCT(ckks1.CTMultFloat(ct, 1))
+		ckks1.RS(&ct)
+	}
+
+	ct = mustCT(ckks1.Bootstrap(ct, params, keys))
+	err := compare(enc.Decode(ckks1.Decrypt(ct, sk)), v.Copy())
+	fmt.Printf("level after bootstrapping : %d, max norm of error : %e (2^%.1f) \n", ct.L, err, math.Log2(err))
+	if ct.L != levels-params.Depth() || err > math.Pow(2, -12) {
+		t.Fail()
+	}
+
+	// the refreshed ciphertext can be used in further multiplications
+	sq := mustCT(ckks1.CTMult(ct, ct, keys.Evk))
+	ckks1.RS(&sq)
+	expected := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	expected.CoefWiseProd(&v, &v)
+	errSq := compare(enc.Decode(ckks1.Decrypt(sq, sk)), expected)
+	fmt.Printf("max norm of error after one more multiplication : %e \n", errSq)
+	if errSq > math.Pow(2, -10) {
+		t.Fail()
+	}
+}
+
+// returns a vector of n real numbers uniformly chosen in [a, b], as []complex128
+func randRealVect(n int, a, b float64) []complex128 {
+	vect := make([]complex128, n)
+	for i := range vect {
+		vect[i] = complex(a+(b-a)*rand.Float64(), 0)
+	}
+	return vect
+}
+
+// applies f to every entry of v
+func applyReal(v cMat.CMat, f func(float64) float64) cMat.CMat {
+	data := v.GetData()
+	res := make([]complex128, len(data))
+	for i, x := range data {
+		res[i] = complex(f(real(x)), 0)
+	}
+	return cMat.NewCMat(len(res), 1, res)
+}
+
+func TestEvalPoly(t *testing.T) {
+	fmt.Println("TESTING POLYNOMIAL EVALUATION")
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, 8, 60, 40, 3, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, 40), 0)))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	monomial := ckks.NewMonomialPolynomial([]float64{0.5, -1, 0.25, 0, 1, -0.5, 0.125, 0.75})
+	sigmoid := func(x float64) float64 { return 1 / (1 + math.Exp(3.5-x)) }
+	chebyshev, err := ckks.ChebyshevApproximation(sigmoid, 15, 1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		p     ckks.Polynomial
+		a, b  float64
+		f     func(float64) float64
+		depth int
+	}{
+		{monomial, -1, 1, monomial.Eval, 3},
+		{chebyshev, 1, 6, sigmoid, 5},
+	}
+	for _, c := range cases {
+		v := cMat.NewCMat(NN/2, 1, randRealVect(NN/2, c.a, c.b))
+		ct := mustCT(ckks1.EvalPoly(ckks1.Encrypt(mustEncode(enc, &v), pk), c.p, evk))
+		err := compare(enc.Decode(ckks1.Decrypt(ct, sk)), applyReal(v, c.f))
+		fmt.Printf("degree %d : depth %d, levels consumed %d, max norm of error : %e \n", c.p.Degree(), c.p.Depth(), ckks1.L-ct.L, err)
+		if c.p.Depth() != c.depth || ckks1.L-ct.L != c.depth || err > 1e-4 {
+			t.Fail()
+		}
+	}
+}
+
+func TestInverse(t *testing.T) {
+	fmt.Println("TESTING HOMOMORPHIC INVERSE")
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, 10, 60, 40, 3, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, 40), 0)))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	// (1 - 1/8)^(2^7) < 2^-24
+	iterations := 7
+	v := cMat.NewCMat(NN/2, 1, randRealVect(NN/2, 1, 8))
+	ct := mustCT(ckks1.CTInverse(ckks1.Encrypt(mustEncode(enc, &v), pk), [2]float64{1, 8}, iterations, evk))
+
+	err := compare(enc.Decode(ckks1.Decrypt(ct, sk)), applyReal(v, func(x float64) float64 { return 1 / x }))
+	fmt.Printf("levels consumed %d, max norm of error : %e \n", ckks1.L-ct.L, err)
+	if ckks1.L-ct.L != ckks.InverseDepth(iterations) || ct.Scale != complex(math.Pow(2, 40), 0) || err > 1e-5 {
+		t.Fail()
+	}
+}
+
+func TestSqrt(t *testing.T) {
+	fmt.Println("TESTING HOMOMORPHIC SQUARE ROOT AND INVERSE SQUARE ROOT")
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, 14, 60, 40, 3, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, 40), 0)))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	iterations := 4
+	interval := [2]float64{1, 36}
+	v := cMat.NewCMat(NN/2, 1, randRealVect(NN/2, interval[0], interval[1]))
+	ct := ckks1.Encrypt(mustEncode(enc, &v), pk)
+
+	ctInvSqrt := mustCT(ckks1.CTInvSqrt(ct, interval, iterations, evk))
+	errInvSqrt := compare(enc.Decode(ckks1.Decrypt(ctInvSqrt, sk)), applyReal(v, func(x float64) float64 { return 1 / math.Sqrt(x) }))
+	ctSqrt := mustCT(ckks1.CTSqrt(ct, interval, iterations, evk))
+	errSqrt := compare(enc.Decode(ckks1.Decrypt(ctSqrt, sk)), applyReal(v, math.Sqrt))
+
+	fmt.Printf("max norm of errors : %e (inverse square root), %e (square root) \n", errInvSqrt, errSqrt)
+	if ckks1.L-ctInvSqrt.L != ckks.InvSqrtDepth(iterations) || ckks1.L-ctSqrt.L != ckks.SqrtDepth(iterations) {
+		t.Fail()
+	}
+	if errInvSqrt > 1e-5 || errSqrt > 1e-4 {
+		t.Fail()
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	fmt.Println("TESTING STANDARD DEVIATION")
+
+	nbStudents := NN / 2
+	nbCourses := 10
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, 18, 60, 40, 3, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, 40), 0)))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	// grades of each student in every course, the standard deviation is computed per student
+	cts := make([]ckks.CT, nbCourses)
+	grades := make([][]float64, nbStudents)
+	for i := range grades {
+		grades[i] = make([]float64, nbCourses)
+	}
+	for j := 0; j < nbCourses; j++ {
+		v := cMat.NewCMat(nbStudents, 1, randGradesVect(nbStudents))
+		for i, g := range v.GetData() {
+			grades[i][j] = real(g)
+		}
+		cts[j] = ckks1.Encrypt(mustEncode(enc, &v), pk)
+	}
+
+	expected := make([]complex128, nbStudents)
+	for i, g := range grades {
+		mean, sq := 0.0, 0.0
+		for _, x := range g {
+			mean += x / float64(nbCourses)
+			sq += x * x / float64(nbCourses)
+		}
+		expected[i] = complex(math.Sqrt(sq-mean*mean), 0)
+	}
+
+	ctStdDev := mustCT(ckks1.StdDev(cts, evk, [2]float64{0.05, 7}, 4))
+	err := compare(enc.Decode(ckks1.Decrypt(ctStdDev, sk)), cMat.NewCMat(nbStudents, 1, expected))
+	fmt.Printf("level of the result : %d, max norm of errors : %e \n", ctStdDev.L, err)
+	if err > 1e-3 {
+		t.Fail()
+	}
+}
+
+func TestCompare(t *testing.T) {
+	fmt.Println("TESTING SIGN, COMPARISON, MAX, MIN AND ARGMAX")
+
+	// in every slot, the messages are a permutation of values at distance >= 0.5 from each other
+	nbCts := 4
+	values := []float64{-0.75, -0.2, 0.3, 0.8}
+	params, err := ckks.SignParametersFor(0.125, 1e-4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("sign : Dg = %d, Df = %d, depth %d, error %e, max error %e \n", params.Dg, params.Df, params.Depth(), params.Error(0.125), params.MaxError())
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, ckks.MaxDepth(nbCts, params), 60, 40, 4, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, 40), 0)))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	data := make([][]complex128, nbCts)
+	for i := range data {
+		data[i] = make([]complex128, NN/2)
+	}
+	for j := 0; j < NN/2; j++ {
+		for i, k := range rand.Perm(nbCts) {
+			data[i][j] = complex(values[k], 0)
+		}
+	}
+	cts := make([]ckks.CT, nbCts)
+	for i := range cts {
+		v := cMat.NewCMat(NN/2, 1, data[i])
+		cts[i] = ckks1.Encrypt(mustEncode(enc, &v), pk)
+	}
+
+	expected := func(f func(j int) float64) cMat.CMat {
+		res := make([]complex128, NN/2)
+		for j := range res {
+			res[j] = complex(f(j), 0)
+		}
+		return cMat.NewCMat(NN/2, 1, res)
+	}
+	check := func(name string, ct ckks.CT, exp cMat.CMat, depth int, tol float64) {
+		err := compare(enc.Decode(ckks1.Decrypt(ct, sk)), exp)
+		fmt.Printf("%s : levels consumed %d, max norm of error : %e \n", name, ckks1.L-ct.L, err)
+		if ckks1.L-ct.L != depth || err > tol {
+			t.Fail()
+		}
+	}
+
+	check("sign", mustCT(ckks1.CTSign(cts[0], params, evk)), expected(func(j int) float64 { return math.Copysign(1, real(data[0][j])) }), params.Depth(), 1e-3)
+	check("compare", mustCT(ckks1.CTCompare(cts[0], cts[1], params, evk)), expected(func(j int) float64 {
+		if real(data[0][j]) > real(data[1][j]) {
+			return 1
+		}
+		return 0
+	}), params.Depth(), 1e-3)
+
+	maxOf := func(j int) float64 { return math.Max(math.Max(real(data[0][j]), real(data[1][j])), math.Max(real(data[2][j]), real(data[3][j]))) }
+	minOf := func(j int) float64 { return math.Min(math.Min(real(data[0][j]), real(data[1][j])), math.Min(real(data[2][j]), real(data[3][j]))) }
+	check("max", mustCT(ckks1.CTMax(cts, params, evk)), expected(maxOf), ckks.MaxDepth(nbCts, params), 1e-3)
+	check("min", mustCT(ckks1.CTMin(cts, params, evk)), expected(minOf), ckks.MaxDepth(nbCts, params), 1e-3)
+
+	// avec un nombre impair de ciphertexts, celui resté seul est aligné sur les résultats du tour
+	maxOf3 := func(j int) float64 { return math.Max(math.Max(real(data[0][j]), real(data[1][j])), real(data[2][j])) }
+	check("max of 3", mustCT(ckks1.CTMax(cts[:3], params, evk)), expected(maxOf3), ckks.MaxDepth(3, params), 1e-3)
+
+	// la politique stricte s'applique aussi au ciphertext resté seul
+	strict := ckks1
+	strict.Alignment = ckks.AlignStrict
+	odd := []ckks.CT{cts[0], cts[1], cts[2]}
+	odd[2].Scale *= 2
+	if _, err := strict.CTMax(odd, params, evk); !errors.Is(err, ckks.ErrScaleMismatch) {
+		t.Errorf("strict CTMax with a leftover of another scale : expected %v, got %v", ckks.ErrScaleMismatch, err)
+	}
+
+	argmax, err := ckks1.CTArgmax(cts, params, evk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range argmax {
+		check(fmt.Sprintf("argmax %d", i), argmax[i], expected(func(j int) float64 {
+			if real(data[i][j]) == maxOf(j) {
+				return 1
+			}
+			return 0
+		}), ckks.ArgmaxDepth(nbCts, params), 1e-3)
+	}
+}
+
+// tests that ckks.SignParametersFor rejects invalid or unreachable tolerances instead of looping forever
+func TestSignParametersBounds(t *testing.T) {
+	fmt.Println("TESTING INVALID AND UNREACHABLE SIGN TOLERANCES")
+
+	cases := [][2]float64{{0, 1e-3}, {-0.1, 1e-3}, {1, 1e-3}, {math.NaN(), 1e-3}, {0.125, 0}, {0.125, -1}, {0.125, math.NaN()},
+		{1e-12, 1e-10}} // la dernière précision ne peut pas être atteinte en 16 compositions
+	for _, c := range cases {
+		if _, err := ckks.SignParametersFor(c[0], c[1]); !errors.Is(err, ckks.ErrInvalidArgument) {
+			t.Errorf("eps = %g, err = %g : got %v instead of ErrInvalidArgument", c[0], c[1], err)
+		}
+	}
+}
+
+// tests the ckks.InnerSum and ckks.InnerProduct functions, and the mean of the grades of all students in a course
+func TestInnerSum(t *testing.T) {
+	fmt.Println("TESTING INNER SUMS AND INNER PRODUCTS")
+
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
+	slots := NN / 2
+
+	va := cMat.NewCMat(slots, 1, randComplexVect(slots, boundForVectorEntries))
+	vb := cMat.NewCMat(slots, 1, randComplexVect(slots, boundForVectorEntries))
+
+	sk := ckks.SKeyGen()
+	pk := ckks.PKeyGen(sk)
+	evk := ckks.EvKeyGen(sk)
+	keys := ckks.RotationKeysGen(sk)
+
+	ctA := ckks.Encrypt(mustEncode(enc, &va), pk)
+	ctB := ckks.Encrypt(mustEncode(enc, &vb), pk)
+
+	for _, bn := range [][2]int{{1, slots}, {1, 7}, {2, 5}, {4, 8}} {
+		batch, n := bn[0], bn[1]
+		expected := make([]complex128, slots)
+		for j := range expected {
+			for i := 0; i < n; i++ {
+				expected[j] += va.GetData()[(j+i*batch)%slots]
+			}
+		}
+
+		vect := enc.Decode(ckks.Decrypt(mustCT(ckks.InnerSum(ctA, batch, n, keys)), sk))
+		err := compare(vect, cMat.NewCMat(slots, 1, expected))
+		fmt.Printf("inner sum with batch %d and n %d, max norm of errors : %f \n", batch, n, err)
+		if err > tolerance {
+			t.Fail()
+		}
+	}
+
+	dot := complex(0, 0)
+	for j := 0; j < slots; j++ {
+		dot += va.GetData()[j] * vb.GetData()[j]
+	}
+	expected := make([]complex128, slots)
+	for j := range expected {
+		expected[j] = dot
+	}
+	ctDot := mustCT(ckks.InnerProduct(ctA, ctB, evk, keys))
+	err := compare(enc.Decode(ckks.Decrypt(ctDot, sk)), cMat.NewCMat(slots, 1, expected))
+	fmt.Printf("inner product, max norm of errors : %f \n", err)
+	if err > tolerance*float64(slots) {
+		t.Fail()
+	}
+
+	// moyenne de toutes les notes d'un cours
+	grades := cMat.NewCMat(slots, 1, randGradesVect(slots))
+	mean := complex(0, 0)
+	for j := 0; j < slots; j++ {
+		mean += grades.GetData()[j] / complex(float64(slots), 0)
+	}
+	for j := range expected {
+		expected[j] = mean
+	}
+	ctGrades := ckks.Encrypt(mustEncode(enc, &grades), pk)
+	ctMean := mustCT(ckks.CTMultFloat(mustCT(ckks.InnerSum(ctGrades, 1, slots, keys)), 1/float64(slots)))
+	ckks.RS(&ctMean)
+	err = compare(enc.Decode(ckks.Decrypt(ctMean, sk)), cMat.NewCMat(slots, 1, expected))
+	fmt.Printf("mean of the course, max norm of errors : %f \n", err)
+	if err > tolerance {
+		t.Fail()
+	}
+}
+
+// tests the ckks.LinearTransform function on square and rectangular matrices
+func TestLinearTransform(t *testing.T) {
+	fmt.Println("TESTING LINEAR TRANSFORMS")
+
+	ckks := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks.N, baseScale))
+	slots := NN / 2
+
+	sk := ckks.SKeyGen()
+	pk := ckks.PKeyGen(sk)
+	keys := ckks.RotationKeysGen(sk)
+
+	va := cMat.NewCMat(slots, 1, randComplexVect(slots, boundForVectorEntries))
+	ct := ckks.Encrypt(mustEncode(enc, &va), pk)
+
+	for _, dims := range [][2]int{{slots, slots}, {5, 12}, {slots, 3}, {1, slots}} {
+		rows, cols := dims[0], dims[1]
+		M := cMat.NewCMat(rows, cols, randComplexVect(rows*cols, 1))
+
+		expected := make([]complex128, slots)
+		for j := 0; j < rows; j++ {
+			for k := 0; k < cols; k++ {
+				expected[j] += M.GetData()[j*cols+k] * va.GetData()[k]
+			}
+		}
+
+		em, err := ckks.EncodeMatrix(M, ct.L)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range em.Rotations() {
+			if _, ok := keys[k]; !ok {
+				keys[k] = ckks.RotationKeyGen(sk, k)
+			}
+		}
+
+		// le second appel utilise les diagonales en cache
+		for i := 0; i < 2; i++ {
+			res := mustCT(ckks.LinearTransform(ct, M, keys))
+			exp := cMat.NewCMat(slots, 1, expected)
+			err := compare(enc.Decode(ckks.Decrypt(res, sk)), exp.Copy())
+			fmt.Printf("%d x %d matrix (%d baby steps), level %d, max norm of errors : %f \n", rows, cols, em.Baby, res.L, err)
+			if err > tolerance || res.L != ct.L-1 || res.Scale != ct.Scale {
+				t.Fail()
+			}
+		}
+	}
+
+	// appels concurrents sur plus de matrices que le cache n'en garde
+	var wg sync.WaitGroup
+	errs := make([]float64, 20)
+	for i := range errs {
+		M := cMat.NewCMat(4, slots, randComplexVect(4*slots, 1))
+		expected := make([]complex128, slots)
+		for j := 0; j < 4; j++ {
+			for k := 0; k < slots; k++ {
+				expected[j] += M.GetData()[j*slots+k] * va.GetData()[k]
+			}
+		}
+		wg.Add(1)
+		go func(i int, M cMat.CMat, expected []complex128) {
+			defer wg.Done()
+			for r := 0; r < 2; r++ {
+				res, err := ckks.LinearTransform(ct, M, keys)
+				if err != nil {
+					errs[i] = math.Inf(1)
+					return
+				}
+				exp := cMat.NewCMat(slots, 1, expected)
+				errs[i] = math.Max(errs[i], compare(enc.Decode(ckks.Decrypt(res, sk)), exp.Copy()))
+			}
+		}(i, M, expected)
+	}
+	wg.Wait()
+	maxErr := 0.0
+	for _, err := range errs {
+		maxErr = math.Max(maxErr, err)
+	}
+	fmt.Printf("%d concurrent transforms, max norm of errors : %f \n", len(errs), maxErr)
+	if maxErr > tolerance {
+		t.Fail()
+	}
+}
+
+// tests the alignment of operands of different levels and scales, and the strict policy
+func TestAlignment(t *testing.T) {
+	fmt.Println("TESTING SCALE AND LEVEL ALIGNMENT")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	enc2 := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale*1.5))
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	vb := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	sum := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	sum.Add(&va, &vb)
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+
+	ctA := ckks1.Encrypt(mustEncode(enc, &va), pk)
+	ctB := ckks1.Encrypt(mustEncode(enc, &vb), pk)
+
+	// ctB à l'échelle 3 * delta, au niveau L - 1, à l'échelle 1.5 * delta
+	ctB3 := ctB
+	ctB3.CTIncScale(big.NewInt(3))
+	ctBLow := mustCT(ckks1.CTMultConst(ctB, 1))
+	ckks1.RS(&ctBLow)
+	ctB15 := ckks1.Encrypt(mustEncode(enc2, &vb), pk)
+
+	cases := []struct {
+		name  string
+		ct    ckks.CT
+		level int
+	}{
+		{"integer ratio of scales", ctB3, ckks1.L},
+		{"lower level", ctBLow, ckks1.L - 1},
+		{"non integer ratio of scales", ctB15, ckks1.L - 1},
+	}
+	for _, c := range cases {
+		if ckks1.CheckOperands(ctA, c.ct) == nil {
+			t.Errorf("%s : mismatch not detected", c.name)
+		}
+		res := mustCT(ckks1.CTAdd(ctA, c.ct))
+		err := compare(enc.Decode(ckks1.Decrypt(res, sk)), sum.Copy())
+		fmt.Printf("%s : level %d, max norm of errors : %f \n", c.name, res.L, err)
+		if err > tolerance || res.L != c.level {
+			t.Fail()
+		}
+	}
+
+	ptB := mustEncode(enc2, &vb)
+	err := compare(enc.Decode(ckks1.Decrypt(mustCT(ckks1.CTAddPlain(ctA, ptB)), sk)), sum.Copy())
+	fmt.Printf("plaintext of another scale, max norm of errors : %f \n", err)
+	if err > tolerance {
+		t.Fail()
+	}
+
+	ckks1.Alignment = ckks.AlignStrict
+	for _, c := range cases {
+		if _, err := ckks1.CTAdd(ctA, c.ct); !errors.Is(err, ckks.ErrScaleMismatch) && !errors.Is(err, ckks.ErrLevelMismatch) {
+			t.Errorf("%s : strict policy did not return the mismatch, got %v", c.name, err)
+		}
+	}
+	if ckks1.CheckOperands(ctA, ctB) != nil {
+		t.Fail()
+	}
+	mustCT(ckks1.CTAdd(ctA, ctB))
+}
+
+// tests that automatic alignment rejects scales too far apart to be corrected by a constant
+func TestAlignmentScaleRange(t *testing.T) {
+	fmt.Println("TESTING ALIGNMENT OF WIDELY DIFFERENT SCALES")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	encLow := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, -5), 0)))
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	ctA := ckks1.Encrypt(mustEncode(enc, &va), pk)
+	ctALow := mustCT(ckks1.DropLevel(ctA, 1))
+	ctA0 := mustCT(ckks1.DropLevel(ctA, nb_levels)) // q_0 a q0_nb_bits bits
+	highScale := real(ctA.Scale) * math.Pow(2, float64(q0_nb_bits-delta_nb_bits)) // multiple entier de l'échelle de ctA, au-delà de q_0/2
+
+	// seules les échelles diffèrent : les opérandes sont des copies de ctA et ctALow
+	withScale := func(ct ckks.CT, scale float64) ckks.CT {
+		ct.Scale = complex(scale, 0)
+		return ct
+	}
+	cases := []struct {
+		name     string
+		ct1, ct2 ckks.CT
+	}{
+		{"scale above the modulus", ctA, withScale(ctALow, math.Pow(2, 300))},
+		{"overflowing constant", ctA, withScale(ctALow, 1e300)},
+		{"constant rounded to 0", withScale(ctA, math.Pow(2, 45)), ctALow},
+		{"same level, constant rounded to 0", ctA, withScale(ctA, math.Pow(2, 45)*1.3)},
+		{"same level, integer ratio above q_L/2", withScale(ctA0, highScale), ctA0},
+		{"same level, integer ratio above q_L/2 (swapped)", ctA0, withScale(ctA0, highScale)},
+	}
+	for _, c := range cases {
+		if _, err := ckks1.CTAdd(c.ct1, c.ct2); !errors.Is(err, ckks.ErrScaleMismatch) {
+			t.Errorf("%s : got %v instead of ErrScaleMismatch", c.name, err)
+		}
+	}
+	if _, err := ckks1.CTAddPlain(ctA, mustEncode(encLow, &va)); !errors.Is(err, ckks.ErrScaleMismatch) {
+		t.Errorf("plaintext : got %v instead of ErrScaleMismatch", err)
+	}
+	encHigh := mustEncoder(encoder.NewEncoder(ckks1.N, complex(highScale, 0)))
+	if _, err := ckks1.CTAddPlain(ctA0, mustEncode(encHigh, &va)); !errors.Is(err, ckks.ErrScaleMismatch) {
+		t.Errorf("plaintext, integer ratio above q_L/2 : got %v instead of ErrScaleMismatch", err)
+	}
+}
+
+// tests the ckks.DropLevel function : the message and the scale are kept
+func TestDropLevel(t *testing.T) {
+	fmt.Println("TESTING LEVEL DROPPING")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	vb := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	sum := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	sum.Add(&va, &vb)
+
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	ctA := ckks1.Encrypt(mustEncode(enc, &va), pk)
+	ctB := ckks1.Encrypt(mustEncode(enc, &vb), pk)
+
+	for _, k := range []int{0, 1, 3, nb_levels} {
+		ct := mustCT(ckks1.DropLevel(ctA, k))
+		err := compare(enc.Decode(ckks1.Decrypt(ct, sk)), va.Copy())
+		fmt.Printf("%d levels dropped, max norm of errors : %f \n", k, err)
+		if err > tolerance || ct.L != ckks1.L-k || ct.Scale != ctA.Scale || ct.Mod.Cmp(ct.A.Modulus()) != 0 {
+			t.Fail()
+		}
+	}
+
+	// un ciphertext frais et un ciphertext multiplié puis divisé par q_L
+	ctB = mustCT(ckks1.CTMultConst(ctB, 1))
+	ckks1.RS(&ctB)
+	ctSum := mustCT(ckks1.CTAdd(mustCT(ckks1.DropLevel(ctA, 1)), ctB))
+	err := compare(enc.Decode(ckks1.Decrypt(ctSum, sk)), sum.Copy())
+	fmt.Printf("sum of a fresh and a rescaled ciphertext, max norm of errors : %f \n", err)
+	if err > tolerance {
+		t.Fail()
+	}
+
+	if _, err := ckks1.DropLevel(ctA, nb_levels+1); !errors.Is(err, ckks.ErrLevelExhausted) {
+		t.Fail()
+	}
+}
+
+// tests the sentinel errors returned on invalid inputs
+func TestErrors(t *testing.T) {
+	fmt.Println("TESTING ERRORS")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	ct := ckks1.Encrypt(mustEncode(enc, &va), pk)
+	ct0 := mustCT(ckks1.DropLevel(ct, ckks1.L))
+	ct3 := ct
+	ct3.CTIncScale(big.NewInt(3))
+	prod := mustCT(ckks1.CTMultNoRelin(ct, ct))
+
+	short := cMat.NewCMat(NN/4, 1, make([]complex128, NN/4))
+	_, errEncode := enc.Encode(&short)
+	_, errCol := va.GetCol(1)
+	_, errMult := new(cMat.CMat).Mult(&va, &va)
+	_, _, errDiv := poly.PolyDiv(poly.NewPoly([]*big.Int{big.NewInt(1), big.NewInt(1)}), poly.ZeroPoly(1))
+	_, errPoly := ckks1.EvalPoly(ct0, ckks.NewMonomialPolynomial([]float64{0, 1, 1}), evk)
+	_, errRotate := ckks1.CTRotate(ct, 3, ckks.RotationKeys{})
+	_, errRelin := ckks1.CTMultNoRelin(prod, ct)
+	_, errMean := ckks1.Mean(nil)
+	strict := ckks1
+	strict.Alignment = ckks.AlignStrict
+	_, errAdd := strict.CTAdd(ct, ct3)
+
+	cases := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{"Encode of a vector of wrong dimension", errEncode, ckks.ErrDimension},
+		{"GetCol out of range", errCol, cMat.ErrIndexOutOfRange},
+		{"Mult of matrices of wrong dimensions", errMult, cMat.ErrDimension},
+		{"PolyDiv by zero", errDiv, poly.ErrDivisionByZero},
+		{"RS at level 0", ckks1.RS(&ct0), ckks.ErrLevelExhausted},
+		{"CTIncScale by a negative factor", ct.CTIncScale(big.NewInt(-1)), ckks.ErrInvalidArgument},
+		{"EvalPoly without enough levels", errPoly, ckks.ErrLevelExhausted},
+		{"CTRotate without key", errRotate, ckks.ErrMissingKey},
+		{"CTMultNoRelin of a degree 2 ciphertext", errRelin, ckks.ErrNotRelinearized},
+		{"Mean of an empty list", errMean, ckks.ErrInvalidArgument},
+		{"strict CTAdd of different scales", errAdd, ckks.ErrScaleMismatch},
+	}
+	for _, c := range cases {
+		fmt.Printf("%s : %v \n", c.name, c.err)
+		if !errors.Is(c.err, c.target) {
+			t.Errorf("%s : expected %v, got %v", c.name, c.target, c.err)
+		}
+	}
+	if ct0.L != 0 {
+		t.Error("a failed RS must not modify the ciphertext")
+	}
+}
+
+// tests that ciphertexts, plaintexts and keys of another instance (or empty keys) are rejected instead of panicking
+func TestForeignOperands(t *testing.T) {
+	fmt.Println("TESTING OPERANDS OF ANOTHER INSTANCE")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	ckks2 := mustCKKS(ckks.NewCKKS(2*NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc1 := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	enc2 := mustEncoder(encoder.NewEncoder(ckks2.N, baseScale))
+	sk1, sk2 := ckks1.SKeyGen(), ckks2.SKeyGen()
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	vb := cMat.NewCMat(NN, 1, randComplexVect(NN, boundForVectorEntries))
+	ct := ckks1.Encrypt(mustEncode(enc1, &va), ckks1.PKeyGen(sk1))
+	pt2 := mustEncode(enc2, &vb)
+	ct2 := ckks2.Encrypt(pt2, ckks2.PKeyGen(sk2))
+	ctMod := ct
+	ctMod.Mod = new(big.Int).Add(ct.Mod, big.NewInt(1))
+
+	errOf := func(_ interface{}, err error) error { return err }
+	empty := ckks.RotationKeys{}
+	for k := 1; k < NN/2; k <<= 1 {
+		empty[k] = ckks.SwitchingKey{}
+	}
+
+	cases := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{"CTRotate with an empty key", errOf(ckks1.CTRotate(ct, 1, empty)), ckks.ErrMissingKey},
+		{"CTRotate with a key of another instance", errOf(ckks1.CTRotate(ct, 1, ckks2.RotationKeysGen(sk2))), ckks.ErrMissingKey},
+		{"InnerSum with empty keys", errOf(ckks1.InnerSum(ct, 1, NN/2, empty)), ckks.ErrMissingKey},
+		{"CTConjugate with a key of another instance", errOf(ckks1.CTConjugate(ct, ckks2.ConjugationKeyGen(sk2))), ckks.ErrMissingKey},
+		{"CTRotate of a ciphertext of another instance", errOf(ckks1.CTRotate(ct2, 1, ckks1.RotationKeysGen(sk1))), ckks.ErrLevelMismatch},
+		{"CTAdd of a ciphertext of another instance", errOf(ckks1.CTAdd(ct, ct2)), ckks.ErrLevelMismatch},
+		{"CTAdd of a ciphertext with a wrong modulus", errOf(ckks1.CTAdd(ctMod, ct)), ckks.ErrLevelMismatch},
+		{"CTMultPlain by a plaintext of another instance", errOf(ckks1.CTMultPlain(ct, pt2)), ckks.ErrDimension},
+		{"CTAddPlain of a plaintext of another instance", errOf(ckks1.CTAddPlain(ct, pt2)), ckks.ErrDimension},
+		{"CTMultPlain of a ciphertext of another instance", errOf(ckks1.CTMultPlain(ct2, mustEncode(enc1, &va))), ckks.ErrLevelMismatch},
+		{"CTAddConst of a ciphertext of another instance", errOf(ckks1.CTAddConst(ct2, 1)), ckks.ErrLevelMismatch},
+		{"CTMultConst of a ciphertext of another instance", errOf(ckks1.CTMultConst(ct2, 1)), ckks.ErrLevelMismatch},
+		{"Relinearize with a key of another instance", errOf(ckks1.Relinearize(mustCT(ckks1.CTMultNoRelin(ct, ct)), ckks2.EvKeyGen(sk2))), ckks.ErrMissingKey},
+	}
+	for _, c := range cases {
+		fmt.Printf("%s : %v \n", c.name, c.err)
+		if !errors.Is(c.err, c.target) {
+			t.Errorf("%s : expected %v, got %v", c.name, c.target, c.err)
+		}
+	}
+}
+
+// tests that constructors and functions return ErrInvalidArgument on invalid parameters instead of panicking
+func TestInvalidArguments(t *testing.T) {
+	fmt.Println("TESTING INVALID PARAMETERS")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, 1))
+	ct := ckks1.Encrypt(mustEncode(enc, &va), pk)
+
+	errOf := func(_ interface{}, err error) error { return err }
+	ctHuge := ct
+	ctHuge.Scale = complex(math.Pow(2, 45)*1.3, 0) // trop loin de l'échelle de ct pour être alignée
+	params := ckks.SignParameters{Dg: 0, Df: 1}
+	badBootstrapping := ckks.BootstrappingParameters{K: 12, Degree: 0, DoubleAngle: 3}
+
+	cases := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{"NewCKKSWithDnum with dnum = 0", errOf(ckks.NewCKKSWithDnum(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, 0, s2)), ckks.ErrInvalidArgument},
+		{"NewCKKSWithDnum with dnum > L+1", errOf(ckks.NewCKKSWithDnum(NN, h, 2, q0_nb_bits, delta_nb_bits, 4, s2)), ckks.ErrInvalidArgument},
+		{"NewCKKS with N not a power of 2", errOf(ckks.NewCKKS(100, h, nb_levels, q0_nb_bits, delta_nb_bits, s2)), ckks.ErrInvalidArgument},
+		{"NewCKKS with primes of 70 bits", errOf(ckks.NewCKKS(NN, h, nb_levels, 70, delta_nb_bits, s2)), ckks.ErrInvalidArgument},
+		{"NewCKKS without enough primes", errOf(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, 8, s2)), ckks.ErrInvalidArgument},
+		{"NewCKKS with H > N", errOf(ckks.NewCKKS(NN, NN+1, nb_levels, q0_nb_bits, delta_nb_bits, s2)), ckks.ErrInvalidArgument},
+		{"GeneratePrimes of 62 bits", errOf(poly.GeneratePrimes(62, NN, 1, nil)), poly.ErrInvalidArgument},
+		{"GeneratePrimes with N not a power of 2", errOf(poly.GeneratePrimes(40, 3, 1, nil)), poly.ErrInvalidArgument},
+		{"NewEncoder with N not a power of 2", errOf(encoder.NewEncoder(100, baseScale)), encoder.ErrInvalidArgument},
+		{"NewEncoder with a zero scale", errOf(encoder.NewEncoder(NN, 0)), encoder.ErrInvalidArgument},
+		{"NewChebyshevPolynomial on an empty interval", errOf(ckks.NewChebyshevPolynomial([]float64{1, 2}, 1, 1)), ckks.ErrInvalidArgument},
+		{"ChebyshevApproximation of negative degree", errOf(ckks.ChebyshevApproximation(math.Exp, -1, 0, 1)), ckks.ErrInvalidArgument},
+		{"EvalPoly on an empty interval", errOf(ckks1.EvalPoly(ct, ckks.Polynomial{Coeffs: []float64{1, 2}, Basis: ckks.ChebyshevBasis, Interval: [2]float64{2, 2}}, evk)), ckks.ErrInvalidArgument},
+		{"EvalMod of degree 0", errOf(ckks1.EvalMod(ct, badBootstrapping, evk)), ckks.ErrInvalidArgument},
+		{"CTMax of unalignable scales", errOf(ckks1.CTMax([]ckks.CT{ct, ctHuge}, params, evk)), ckks.ErrScaleMismatch},
+		{"CTArgmax of unalignable scales", errOf(ckks1.CTArgmax([]ckks.CT{ct, ctHuge}, params, evk)), ckks.ErrScaleMismatch},
+	}
+	for _, c := range cases {
+		fmt.Printf("%s : %v \n", c.name, c.err)
+		if !errors.Is(c.err, c.target) {
+			t.Errorf("%s : expected %v, got %v", c.name, c.target, c.err)
+		}
+	}
+}
+
+// tests poly operations on operands in different (NTT and coefficient) forms
+func TestMixedForms(t *testing.T) {
+	fmt.Println("TESTING RNS OPERATIONS ON MIXED FORMS")
+
+	N := 16
+	moduli, err := poly.GeneratePrimes(40, N, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Q := poly.ProdModuli(moduli)
+	u := poly.ToRNS(random.RandomPol(N, Q), N, moduli)
+	v := poly.ToRNS(random.RandomPol(N, Q), N, moduli)
+	uNTT, vNTT := poly.NTT(u), poly.NTT(v)
+
+	cases := []struct {
+		name          string
+		got, expected poly.RNSPoly
+	}{
+		{"AddRNS", poly.AddRNS(uNTT, v), poly.AddRNS(uNTT, vNTT)},
+		{"SubRNS", poly.SubRNS(u, vNTT), poly.SubRNS(u, v)},
+		{"MultModRNS", poly.MultModRNS(u, vNTT), poly.MultModRNS(uNTT, vNTT)},
+		{"ConvertBasis", poly.ConvertBasis(uNTT, moduli[:1]), poly.ConvertBasis(u, moduli[:1])},
+	}
+	for _, c := range cases {
+		if !equalRNS(c.got, c.expected) {
+			t.Errorf("%s : wrong result on mixed forms", c.name)
+		}
+	}
+}
+
+// tests cMat.Sub, which used to subtract <a> from itself instead of <b>
+func TestCMatSub(t *testing.T) {
+	fmt.Println("TESTING MATRIX SUBTRACTION")
+
+	a := cMat.NewCMat(4, 1, randComplexVect(4, boundForVectorEntries))
+	b := cMat.NewCMat(4, 1, randComplexVect(4, boundForVectorEntries))
+	var diff cMat.CMat
+	if _, err := diff.Sub(&a, &b); err != nil {
+		t.Fatal(err)
+	}
+	for i, d := range diff.GetData() {
+		if cmplx.Abs(d-(a.GetData()[i]-b.GetData()[i])) > 1e-9 {
+			t.Fatalf("slot %d : %v - %v gave %v", i, a.GetData()[i], b.GetData()[i], d)
+		}
+	}
+}
+
+// tests the standard parameter sets and the security checks of ckks.NewCKKSFromParameters
+func TestParameters(t *testing.T) {
+	fmt.Println("TESTING STANDARD PARAMETERS")
+
+	for _, security := range []ckks.SecurityLevel{ckks.Security128, ckks.Security192, ckks.Security256} {
+		for logN := ckks.MinLogN; logN <= ckks.MaxLogN; logN++ {
+			params, err := ckks.DefaultParameters(logN, security)
+			if err != nil {
+				continue
+			}
+			max, _ := ckks.MaxLogQP(logN, security)
+			fmt.Printf("N = 2^%d, %d bits : L = %d, log QP <= %d (max %d) \n", logN, security, params.L, params.LogQP(), max)
+			if params.Validate() != nil || params.LogQP() > max || params.EstimatedSecurity() < security || params.L < 1 {
+				t.Errorf("N = 2^%d, %d bits : invalid standard parameters", logN, security)
+			}
+		}
+	}
+	if _, err := ckks.DefaultParameters(10, ckks.Security128); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("no standard parameters expected for N = 2^10")
+	}
+
+	// plus de niveaux, une sk creuse, une erreur trop petite, une sécurité exigée plus haute
+	moreLevels := ckks.PN13QP218
+	moreLevels.L++
+	sparse := ckks.PN13QP218
+	sparse.H = 64
+	smallError := ckks.PN13QP218
+	smallError.S2 = 3.2
+	higher := ckks.PN13QP218
+	higher.Security = ckks.Security192
+	for _, params := range []ckks.Parameters{moreLevels, sparse, smallError, higher} {
+		if _, err := ckks.NewCKKSFromParameters(params); !errors.Is(err, ckks.ErrInsecureParameters) {
+			t.Errorf("insecure parameters accepted : %v", err)
+		}
+	}
+	badDnum := ckks.PN13QP218
+	badDnum.Dnum = 0
+	if _, err := ckks.NewCKKSFromParameters(badDnum); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Errorf("invalid parameters accepted : %v", err)
+	}
+
+	// une instance standard chiffre et multiplie correctement
+	params := ckks.PN13QP218
+	ckks1, err := ckks.NewCKKSFromParameters(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logQP := new(big.Int).Mul(ckks1.Q, ckks1.P).BitLen()
+	if logQP > params.LogQP() || ckks1.EstimatedSecurity() < ckks.Security128 {
+		t.Errorf("log QP = %d, estimated security %d", logQP, ckks1.EstimatedSecurity())
+	}
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, complex(math.Pow(2, float64(params.LogDelta)), 0)))
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	slots := ckks1.N / 2
+	va := cMat.NewCMat(slots, 1, randComplexVect(slots, 4))
+	vb := cMat.NewCMat(slots, 1, randComplexVect(slots, 4))
+	prod := cMat.NewCMat(slots, 1, make([]complex128, slots))
+	prod.CoefWiseProd(&va, &vb)
+
+	ct := mustCT(ckks1.CTMult(ckks1.Encrypt(mustEncode(enc, &va), pk), ckks1.Encrypt(mustEncode(enc, &vb), pk), evk))
+	if err := ckks1.RS(&ct); err != nil {
+		t.Fatal(err)
+	}
+	errProd := compare(enc.Decode(ckks1.Decrypt(ct, sk)), prod)
+	fmt.Printf("N = %d, log QP = %d : max norm of errors on a product : %f \n", ckks1.N, logQP, errProd)
+	if errProd > tolerance {
+		t.Fail()
+	}
+}
+
+// tests the secret key encryption and the reconstruction of a ciphertext from its seed
+func TestEncryptSK(t *testing.T) {
+	fmt.Println("TESTING SECRET KEY ENCRYPTION")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	vb := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	sum := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	sum.Add(&va, &vb)
+
+	ctA := ckks1.EncryptSK(mustEncode(enc, &va), sk)
+	ctB := ckks1.EncryptSK(mustEncode(enc, &vb), sk)
+	err := compare(enc.Decode(ckks1.Decrypt(ctA, sk)), va.Copy())
+	fmt.Printf("decryption, max norm of errors : %f \n", err)
+	if err > tolerance || len(ctA.Seed) != random.SeedSize || bytes.Equal(ctA.Seed, ctB.Seed) {
+		t.Fail()
+	}
+
+	// le ciphertext reconstruit à partir de (B, graine) est le même
+	rebuilt, errSeed := ckks1.CTFromSeed(ctA.Seed, ctA.B, ctA.Scale)
+	if errSeed != nil {
+		t.Fatal(errSeed)
+	}
+	for i := range rebuilt.A.Coefs {
+		for j := range rebuilt.A.Coefs[i] {
+			if rebuilt.A.Coefs[i][j] != ctA.A.Coefs[i][j] {
+				t.Fatal("the polynomial expanded from the seed differs")
+			}
+		}
+	}
+
+	// au niveau inférieur, A est la restriction du polynôme tiré de la graine
+	low := mustCT(ckks1.DropLevel(ctA, 3))
+	rebuiltLow, _ := ckks1.CTFromSeed(ctA.Seed, low.B, low.Scale)
+	err = compare(enc.Decode(ckks1.Decrypt(rebuiltLow, sk)), va.Copy())
+	fmt.Printf("rebuilt at level %d, max norm of errors : %f \n", rebuiltLow.L, err)
+	if err > tolerance {
+		t.Fail()
+	}
+
+	// les ciphertexts chiffrés par la sk et par la pk se combinent, le résultat n'a plus de graine
+	ctSum := mustCT(ckks1.CTAdd(ctA, ckks1.Encrypt(mustEncode(enc, &vb), pk)))
+	err = compare(enc.Decode(ckks1.Decrypt(ctSum, sk)), sum.Copy())
+	fmt.Printf("sum with a public key ciphertext, max norm of errors : %f \n", err)
+	if err > tolerance || ctSum.Seed != nil {
+		t.Fail()
+	}
+	scaled := ctA
+	scaled.CTIncScale(big.NewInt(2))
+	if scaled.Seed != nil || ctA.Seed == nil {
+		t.Error("the seed must be dropped when A changes")
+	}
+
+	if _, err := ckks1.CTFromSeed(ctA.Seed[:16], ctA.B, ctA.Scale); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("short seed accepted")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	fmt.Println("TESTING SERIALIZATION")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+	rtks := ckks.RotationKeys{1: ckks1.RotationKeyGen(sk, 1), 2: ckks1.RotationKeyGen(sk, 2)}
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	prod := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	prod.CoefWiseProd(&va, &va)
+
+	// polynôme à coefficients de signes quelconques
+	pol := poly.NewPoly([]*big.Int{big.NewInt(-3), big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), 100)})
+	data, _ := pol.MarshalBinary()
+	var pol2 poly.Poly
+	if err := pol2.UnmarshalBinary(data); err != nil || len(pol2.Coefs) != 3 ||
+		pol2.Coefs[0].Int64() != -3 || pol2.Coefs[2].Cmp(pol.Coefs[2]) != 0 {
+		t.Error("Poly round trip failed", err)
+	}
+
+	pt := mustEncode(enc, &va)
+	data, _ = pt.MarshalBinary()
+	var pt2 encoder.PT
+	if err := pt2.UnmarshalBinary(data); err != nil || pt2.Scale != pt.Scale {
+		t.Fatal("PT round trip failed", err)
+	}
+	errPT := compare(enc.Decode(pt2), va.Copy())
+	fmt.Printf("plaintext, max norm of errors : %f \n", errPT)
+	if errPT > tolerance {
+		t.Fail()
+	}
+
+	// clés et ciphertexts écrits à la suite dans un flux, puis relus par une instance de mêmes paramètres
+	var buf bytes.Buffer
+	ct := ckks1.Encrypt(pt, pk)
+	ctSeeded := ckks1.EncryptSK(pt, sk)
+	for _, obj := range []io.WriterTo{sk, pk, evk, rtks, ct, ctSeeded} {
+		if _, err := obj.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sk2 ckks.SecretKey
+	var pk2 ckks.PublicKey
+	var evk2 ckks.SwitchingKey
+	var rtks2 ckks.RotationKeys
+	var ct2, ctSeeded2 ckks.CT
+	for _, obj := range []encoding.BinaryUnmarshaler{&sk2, &pk2, &evk2, &rtks2, &ct2, &ctSeeded2} {
+		if _, err := ckks1.LoadFrom(&buf, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() != 0 || len(rtks2) != 2 || len(evk2) != len(evk) {
+		t.Fatal("keys were not read back")
+	}
+
+	ctProd := mustCT(ckks1.CTMult(ct2, ckks1.Encrypt(pt, pk2), evk2))
+	ckks1.RS(&ctProd)
+	errProd := compare(enc.Decode(ckks1.Decrypt(ctProd, sk2)), prod.Copy())
+	fmt.Printf("product with the read keys, max norm of errors : %f \n", errProd)
+	ctRot := mustCT(ckks1.CTRotate(ctSeeded2, 2, rtks2))
+	errRot := compare(enc.Decode(ckks1.Decrypt(ctRot, sk)), rotate(va, 2))
+	fmt.Printf("rotation with the read keys, max norm of errors : %f \n", errRot)
+	if errProd > tolerance || errRot > tolerance {
+		t.Fail()
+	}
+
+	// ciphertext de degré 2 à un niveau inférieur ; un ciphertext chiffré par la sk ne transporte que B et la graine
+	ctDeg2 := mustCT(ckks1.DropLevel(mustCT(ckks1.CTMultNoRelin(ct, ct)), 2))
+	data, _ = ctDeg2.MarshalBinary()
+	var ctDeg2Read ckks.CT
+	if err := ckks1.Load(data, &ctDeg2Read); err != nil || ctDeg2Read.Degree() != 2 || ctDeg2Read.L != ctDeg2.L {
+		t.Fatal("degree 2 round trip failed", err)
+	}
+	ckks1.RS(&ctDeg2Read)
+	errDeg2 := compare(enc.Decode(ckks1.Decrypt(ctDeg2Read, sk)), prod.Copy())
+	fmt.Printf("degree 2 ciphertext, max norm of errors : %f \n", errDeg2)
+	full, _ := ct.MarshalBinary()
+	seeded, _ := ctSeeded.MarshalBinary()
+	fmt.Printf("ciphertext size : %d bytes, %d bytes with a seed \n", len(full), len(seeded))
+	if errDeg2 > tolerance || 10*len(seeded) > 6*len(full) {
+		t.Fail()
+	}
+
+	// une instance de paramètres différents refuse les objets
+	ckks2 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits-1, delta_nb_bits, s2))
+	if err := ckks2.Load(full, &ct2); !errors.Is(err, ckks.ErrParameterMismatch) {
+		t.Error("ciphertext loaded into mismatched parameters", err)
+	}
+	skData, _ := sk.MarshalBinary()
+	if err := ckks2.Load(skData, &sk2); !errors.Is(err, ckks.ErrParameterMismatch) {
+		t.Error("key loaded into mismatched parameters", err)
+	}
+	if ckks1.Fingerprint() == ckks2.Fingerprint() {
+		t.Error("different parameters have the same fingerprint")
+	}
+
+	// données corrompues
+	if err := ckks1.Load(full[:len(full)-1], &ct2); !errors.Is(err, ckks.ErrFormat) {
+		t.Error("truncated ciphertext accepted", err)
+	}
+	if err := ckks1.Load(skData, &ct2); !errors.Is(err, ckks.ErrFormat) {
+		t.Error("secret key loaded as a ciphertext", err)
+	}
+
+	// clés tronquées : sans les premiers spéciaux, ou sans le dernier premier de Q pour la pk
+	truncate := func(pair [2]poly.RNSPoly, level int) [2]poly.RNSPoly {
+		return [2]poly.RNSPoly{poly.DropLimbs(pair[0], level), poly.DropLimbs(pair[1], level)}
+	}
+	evkQ := ckks.SwitchingKey{}
+	for _, digit := range evk {
+		pair := truncate([2]poly.RNSPoly{digit.B, digit.A}, nb_levels)
+		evkQ = append(evkQ, ckks.SwitchingKeyDigit{B: pair[0], A: pair[1]})
+	}
+	pkQ := truncate([2]poly.RNSPoly{pk.B, pk.A}, nb_levels-1)
+	var skT ckks.SecretKey
+	var pkT ckks.PublicKey
+	var evkT ckks.SwitchingKey
+	var rtksT ckks.RotationKeys
+	truncated := []struct {
+		name string
+		key  encoding.BinaryMarshaler
+		obj  encoding.BinaryUnmarshaler
+	}{
+		{"secret key", ckks.SecretKey(truncate(sk, nb_levels)), &skT},
+		{"public key", ckks.PublicKey{B: pkQ[0], A: pkQ[1], Seed: pk.Seed}, &pkT},
+		{"switching key", evkQ, &evkT},
+		{"rotation keys", ckks.RotationKeys{1: evkQ}, &rtksT},
+	}
+	for _, c := range truncated {
+		keyData, err := c.key.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ckks1.Load(keyData, c.obj); !errors.Is(err, ckks.ErrFormat) {
+			t.Errorf("truncated %s accepted : %v", c.name, err)
+		}
+	}
+	bad := append([]byte{}, full...)
+	bad[2] = poly.FormatVersion + 1
+	if err := ct2.UnmarshalBinary(bad); !errors.Is(err, ckks.ErrFormat) {
+		t.Error("unknown version accepted", err)
+	}
+
+	// en-têtes de RNSPoly invalides : aucun limb, N nul ou qui n'est pas une puissance de 2, module qui n'est pas
+	// un premier congru à 1 mod 2N
+	headers := []struct {
+		name       string
+		N, nbLimbs uint32
+		ntt        byte
+		q          uint64
+	}{
+		{"no limb", 4, 0, 0, 0},
+		{"N = 0", 0, 1, 0, 17},
+		{"N = 6", 6, 1, 0, 17},
+		{"NTT modulus", 4, 1, 1, 13},
+		{"modulus not 1 mod 2N", 4, 1, 0, 13},
+		{"composite modulus", 4, 1, 0, 25},
+	}
+	for _, c := range headers {
+		w := poly.FrameWriter{}
+		w.Uint32(c.N)
+		w.Uint32(c.nbLimbs)
+		w.Byte(c.ntt)
+		for i := uint32(0); i < c.nbLimbs; i++ {
+			w.Uint64(c.q)
+			w.Bytes(make([]byte, c.N))
+		}
+		var rns poly.RNSPoly
+		if err := rns.UnmarshalBinary(w.Frame(poly.TagRNSPoly)); !errors.Is(err, ckks.ErrFormat) {
+			t.Errorf("RNS polynomial with %s accepted : %v", c.name, err)
+		}
+	}
+	// 2^20 limbs de degré 2^30 annoncés dans un cadre de quelques octets
+	w := poly.FrameWriter{}
+	w.Uint32(1 << 30)
+	w.Uint32(1 << 20)
+	w.Byte(0)
+	w.Uint64(17)
+	var rns poly.RNSPoly
+	if err := rns.UnmarshalBinary(w.Frame(poly.TagRNSPoly)); !errors.Is(err, ckks.ErrFormat) {
+		t.Error("RNS polynomial with too many limbs accepted", err)
+	}
+	w = poly.FrameWriter{}
+	w.Uint32(4)
+	w.Uint32(1)
+	w.Byte(0)
+	w.Uint64(17)
+	w.Bytes(make([]byte, 4))
+	if err := rns.UnmarshalBinary(w.Frame(poly.TagRNSPoly)); err != nil {
+		t.Error("valid RNS polynomial rejected", err)
+	}
+
+	// un en-tête annonçant un cadre de 2 GiB, suivi de quelques octets : la lecture échoue sans allouer le cadre annoncé
+	header := poly.NewFrame(poly.TagRNSPoly, nil)
+	binary.LittleEndian.PutUint64(header[4:], math.MaxInt32)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, _, errFrame := poly.ReadFrame(bytes.NewReader(append(header, make([]byte, 64)...)))
+	runtime.ReadMemStats(&after)
+	if !errors.Is(errFrame, io.ErrUnexpectedEOF) || after.TotalAlloc-before.TotalAlloc > 1<<20 {
+		t.Errorf("truncated frame : %v, %d bytes allocated", errFrame, after.TotalAlloc-before.TotalAlloc)
+	}
+	if _, err := (poly.Poly{Coefs: []*big.Int{big.NewInt(1), nil}}).MarshalBinary(); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("nil coefficient accepted", err)
+	}
+	if _, err := (poly.RNSPoly{}).MarshalBinary(); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("empty RNS polynomial accepted", err)
+	}
+}
+
+// indique si les polynômes <u> et <v> ont les mêmes limbs
+func equalRNS(u, v poly.RNSPoly) bool {
+	if len(u.Coefs) != len(v.Coefs) {
+		return false
+	}
+	for i := range u.Coefs {
+		for j := range u.Coefs[i] {
+			if u.Coefs[i][j] != v.Coefs[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestSeededKeys(t *testing.T) {
+	fmt.Println("TESTING SEED-COMPRESSED KEYS")
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, 3, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	evk := ckks1.EvKeyGen(sk)
+
+	if len(pk.Seed) != random.SeedSize {
+		t.Fatal("the public key is not drawn from a seed")
+	}
+	for _, digit := range evk {
+		if len(digit.Seed) != random.SeedSize || bytes.Equal(digit.Seed, pk.Seed) {
+			t.Fatal("the evaluation key is not drawn from fresh seeds")
+		}
+	}
+
+	// la clé sérialisée ne contient que les graines des a_j : sa taille est environ la moitié de la clé complète
+	data, _ := evk.MarshalBinary()
+	full := append(ckks.SwitchingKey{}, evk...)
+	for j := range full {
+		full[j].Seed = nil
+	}
+	fullData, _ := full.MarshalBinary()
+	info := ckks1.KeySwitchingInfo()
+	fmt.Printf("evaluation key : %d bytes, %d bytes without seeds, estimated %d bytes \n", len(data), len(fullData), info.KeySize)
+	if 10*len(data) > 6*len(fullData) || len(data) < info.KeySize || len(data) > info.KeySize+200*info.Dnum {
+		t.Fail()
+	}
+	if evk[0].Seed == nil {
+		t.Fatal("clearing the seeds of a copy modified the key")
+	}
+
+	// les clés relues sont celles d'origine
+	var evk2 ckks.SwitchingKey
+	var pk2 ckks.PublicKey
+	pkData, _ := pk.MarshalBinary()
+	if err := ckks1.Load(data, &evk2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ckks1.Load(pkData, &pk2); err != nil {
+		t.Fatal(err)
+	}
+	for j := range evk {
+		if !equalRNS(evk2[j].A, evk[j].A) || !equalRNS(evk2[j].B, evk[j].B) || !bytes.Equal(evk2[j].Seed, evk[j].Seed) {
+			t.Fatal("the evaluation key expanded from the seeds differs")
+		}
+	}
+	if !equalRNS(pk2.A, pk.A) || !bytes.Equal(pk2.Seed, pk.Seed) {
+		t.Fatal("the public key expanded from the seed differs")
+	}
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForVectorEntries))
+	prod := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	prod.CoefWiseProd(&va, &va)
+	ct := ckks1.Encrypt(mustEncode(enc, &va), pk2)
+	ctProd := mustCT(ckks1.CTMult(ct, ct, evk2))
+	ckks1.RS(&ctProd)
+	err := compare(enc.Decode(ckks1.Decrypt(ctProd, sk)), prod.Copy())
+	fmt.Printf("product with the expanded keys, max norm of errors : %f \n", err)
+	if err > tolerance {
+		t.Fail()
+	}
+
+	// un ciphertext chiffré par la sk garde la graine de A à un niveau inférieur
+	low := mustCT(ckks1.DropLevel(ckks1.EncryptSK(mustEncode(enc, &va), sk), 2))
+	lowData, _ := low.MarshalBinary()
+	var low2 ckks.CT
+	if err := ckks1.Load(lowData, &low2); err != nil {
+		t.Fatal(err)
+	}
+	err = compare(enc.Decode(ckks1.Decrypt(low2, sk)), va.Copy())
+	fmt.Printf("ciphertext at level %d (%d bytes), max norm of errors : %f \n", low2.L, len(lowData), err)
+	if err > tolerance || low.Seed == nil || !bytes.Equal(low2.Seed, low.Seed) {
+		t.Fail()
+	}
+
+	// le flux des graines est fixé par le format : AES-256-CTR, compteur initial nul (vecteur connu de AES-256)
+	stream := make([]byte, 16)
+	random.NewPRNG(make([]byte, random.SeedSize)).Read(stream)
+	if hex.EncodeToString(stream) != "dc95c078a2408989ad48a21492842087" {
+		t.Error("the seed expansion differs from the serialized format", hex.EncodeToString(stream))
+	}
+
+	// les cadres de la version précédente restent lisibles
+	ptData, _ := mustEncode(enc, &va).MarshalBinary()
+	ptData[2] = 1
+	var pt encoder.PT
+	if err := pt.UnmarshalBinary(ptData); err != nil {
+		t.Error("version 1 frame rejected", err)
+	}
+}
+
+// tests the n-out-of-n protocols : collective public and relinearization keys, collective decryption
+func TestMultiparty(t *testing.T) {
+	fmt.Println("TESTING MULTIPARTY KEY GENERATION AND DECRYPTION")
+
+	ckks1 := mustCKKS(ckks.NewCKKSWithDnum(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, 2, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	nbParties := 3
+	crs := random.NewSeed()
+
+	// chaque partie tire sa sk et publie sa part de la public key
+	sks := make([]ckks.SecretKey, nbParties)
+	pkShares := make([]ckks.PublicKey, nbParties)
+	for i := range sks {
+		sks[i] = ckks1.SKeyGen()
+		pkShares[i], _ = ckks1.PKeyGenShare(sks[i], crs)
+	}
+	pk, err := ckks1.AggregatePublicKey(pkShares...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// génération de la clé de relinéarisation en deux tours
+	round1 := make([]ckks.RKGShare, nbParties)
+	us := make([]ckks.SecretKey, nbParties)
+	for i := range sks {
+		share, u, _ := ckks1.RKGShareRound1(sks[i], crs)
+		// les parts sont échangées sérialisées
+		data, err := share.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ckks1.Load(data, &round1[i]); err != nil {
+			t.Fatal(err)
+		}
+		us[i] = u
+	}
+	agg1, err := ckks1.AggregateRKGShares(round1...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	round2 := make([]ckks.RKGShare, nbParties)
+	for i := range sks {
+		round2[i], _ = ckks1.RKGShareRound2(sks[i], us[i], agg1)
+	}
+	agg2, _ := ckks1.AggregateRKGShares(round2...)
+	rlk, err := ckks1.RelinearizationKey(agg1, agg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForGradeEntries))
+	vb := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForGradeEntries))
+	prod := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	prod.CoefWiseProd(&va, &vb)
+
+	ctProd := mustCT(ckks1.CTMult(ckks1.Encrypt(mustEncode(enc, &va), pk), ckks1.Encrypt(mustEncode(enc, &vb), pk), rlk))
+	ckks1.RS(&ctProd)
+
+	// déchiffrement collectif : une part par partie, puis leur somme
+	shares := make([]poly.RNSPoly, nbParties)
+	for i := range sks {
+		shares[i], err = ckks1.DecryptionShare(ctProd, sks[i], 1<<8)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	agg, _ := ckks1.AggregateDecryptionShares(shares...)
+	pt, err := ckks1.CombineDecryption(ctProd, agg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errProd := compare(enc.Decode(pt), prod.Copy())
+	fmt.Printf("collective decryption of a product, max norm of errors : %f \n", errProd)
+
+	// sans toutes les parts, le message n'est pas retrouvé
+	partial, _ := ckks1.AggregateDecryptionShares(shares[:nbParties-1]...)
+	ptPartial, _ := ckks1.CombineDecryption(ctProd, partial)
+	errPartial := compare(enc.Decode(ptPartial), prod.Copy())
+	errSingle := compare(enc.Decode(ckks1.Decrypt(ctProd, sks[0])), prod.Copy())
+	fmt.Printf("with %d parties, max norm of errors : %e, with one secret key : %e \n", nbParties-1, errPartial, errSingle)
+	if errProd > tolerance || errPartial < 1 || errSingle < 1 {
+		t.Fail()
+	}
+
+	if _, err := ckks1.PKeyGenShare(sks[0], crs[:8]); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("short common reference string accepted")
+	}
+	if _, err := ckks1.AggregatePublicKey(pkShares[0], ckks1.PKeyGen(sks[1])); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("public key shares with different common polynomials accepted")
+	}
+	if _, err := ckks1.DecryptionShare(mustCT(ckks1.CTMultNoRelin(ctProd, ctProd)), sks[0], 1<<8); !errors.Is(err, ckks.ErrNotRelinearized) {
+		t.Error("decryption share of a degree 2 ciphertext")
+	}
+	low := mustCT(ckks1.DropLevel(ctProd, 1))
+	if _, err := ckks1.CombineDecryption(low, agg); !errors.Is(err, ckks.ErrLevelMismatch) {
+		t.Error("decryption share at a different level accepted")
+	}
+}
+
+// tests the re-encryption of a result under the public key of a third party, by the holders of the key shares
+func TestPublicKeySwitch(t *testing.T) {
+	fmt.Println("TESTING PUBLIC KEY SWITCHING")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	nbParties := 3
+	crs := random.NewSeed()
+
+	sks := make([]ckks.SecretKey, nbParties)
+	pkShares := make([]ckks.PublicKey, nbParties)
+	for i := range sks {
+		sks[i] = ckks1.SKeyGen()
+		pkShares[i], _ = ckks1.PKeyGenShare(sks[i], crs)
+	}
+	pk, _ := ckks1.AggregatePublicKey(pkShares...)
+
+	// le destinataire a sa propre paire de clés
+	skDean := ckks1.SKeyGen()
+	pkDean := ckks1.PKeyGen(skDean)
+
+	// moyenne des notes de chaque enseignant
+	grades := make([]cMat.CMat, nbParties)
+	cts := make([]ckks.CT, nbParties)
+	mean := cMat.NewCMat(NN/2, 1, make([]complex128, NN/2))
+	for i := range grades {
+		grades[i] = cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForGradeEntries))
+		cts[i] = ckks1.Encrypt(mustEncode(enc, &grades[i]), pk)
+		mean.Add(&mean, &grades[i])
+	}
+	mean.Scale(complex(1/float64(nbParties), 0))
+	ctMean := mustCT(ckks1.Mean(cts))
+	ckks1.RS(&ctMean)
+
+	shares := make([]ckks.PCKSShare, nbParties)
+	for i := range sks {
+		var err error
+		if shares[i], err = ckks1.PublicKeySwitchShare(ctMean, sks[i], pkDean, 1<<8); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctDean, err := ckks1.AggregatePublicKeySwitch(ctMean, shares...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errDean := compare(enc.Decode(ckks1.Decrypt(ctDean, skDean)), mean.Copy())
+	fmt.Printf("decryption by the recipient, max norm of errors : %f \n", errDean)
+
+	// les enseignants ne peuvent plus déchiffrer le résultat, même ensemble
+	decShares := make([]poly.RNSPoly, nbParties)
+	for i := range sks {
+		decShares[i], _ = ckks1.DecryptionShare(ctDean, sks[i], 1<<8)
+	}
+	agg, _ := ckks1.AggregateDecryptionShares(decShares...)
+	pt, _ := ckks1.CombineDecryption(ctDean, agg)
+	errTeachers := compare(enc.Decode(pt), mean.Copy())
+	fmt.Printf("collective decryption by the teachers, max norm of errors : %e \n", errTeachers)
+	if errDean > tolerance || errTeachers < 1 {
+		t.Fail()
+	}
+
+	if _, err := ckks1.AggregatePublicKeySwitch(mustCT(ckks1.DropLevel(ctMean, 1)), shares...); !errors.Is(err, ckks.ErrLevelMismatch) {
+		t.Error("key switching shares at a different level accepted")
+	}
+	if _, err := ckks1.PublicKeySwitchShare(ctMean, sks[0], ckks.PublicKey{B: skDean[0], A: skDean[1]}, 1<<8); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("a key defined modulo Q * P accepted as a public key")
+	}
+}
+
+// tests the t-out-of-n decryption with Shamir shares of the secret key
+func TestThresholdDecryption(t *testing.T) {
+	fmt.Println("TESTING THRESHOLD DECRYPTION")
+
+	ckks1 := mustCKKS(ckks.NewCKKS(NN, h, nb_levels, q0_nb_bits, delta_nb_bits, s2))
+	enc := mustEncoder(encoder.NewEncoder(ckks1.N, baseScale))
+	sk := ckks1.SKeyGen()
+	pk := ckks1.PKeyGen(sk)
+	threshold, nbParties := 3, 5
+	shares, err := ckks1.ShareSecretKey(sk, threshold, nbParties)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	va := cMat.NewCMat(NN/2, 1, randComplexVect(NN/2, boundForGradeEntries))
+	ct := mustCT(ckks1.DropLevel(ckks1.Encrypt(mustEncode(enc, &va), pk), 2))
+
+	// les parties du groupe d'indices <group>
+	parties := func(group ...int) []ckks.ThresholdParty {
+		res := []ckks.ThresholdParty{}
+		for _, i := range group {
+			res = append(res, shares[i-1].ThresholdParty)
+		}
+		return res
+	}
+
+	for _, group := range [][]int{{1, 3, 5}, {2, 4, 5}, {1, 2, 3, 4}} {
+		// les sk de Lagrange du groupe somment à la sk partagée
+		var sum poly.RNSPoly
+		partials := []poly.RNSPoly{}
+		for _, i := range group {
+			skLagrange, err := ckks1.LagrangeShare(shares[i-1], parties(group...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum.Coefs == nil {
+				sum = skLagrange[1]
+			} else {
+				sum = poly.AddRNS(sum, skLagrange[1])
+			}
+			partial, err := ckks1.ThresholdDecryptionShare(ct, shares[i-1], parties(group...), 1<<8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			partials = append(partials, partial)
+		}
+		if !equalRNS(sum, sk[1]) {
+			t.Errorf("the Lagrange combination of group %v is not the secret key", group)
+		}
+
+		pt, err := ckks1.ThresholdDecrypt(ct, partials...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		errGroup := compare(enc.Decode(pt), va.Copy())
+		fmt.Printf("decryption by parties %v, max norm of errors : %f \n", group, errGroup)
+
+		// il manque une part
+		ptMissing, _ := ckks1.ThresholdDecrypt(ct, partials[1:]...)
+		errMissing := compare(enc.Decode(ptMissing), va.Copy())
+		if errGroup > tolerance || errMissing < 1 {
+			t.Fail()
+		}
+	}
+
+	if _, err := ckks1.LagrangeShare(shares[0], parties(1, 2)); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("group below the threshold accepted")
+	}
+	if _, err := ckks1.LagrangeShare(shares[0], parties(2, 3, 4)); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("share outside of the group accepted")
+	}
+	if _, err := ckks1.LagrangeShare(shares[0], parties(1, 3, 3)); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("repeated party accepted")
+	}
+	if _, err := ckks1.ShareSecretKey(sk, 4, 3); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("threshold above the number of parties accepted")
+	}
+
+	// une part forgée avec un seuil plus bas n'est pas acceptée dans un groupe trop petit, ni dans un groupe de seuil 3
+	forged := shares[0]
+	forged.Threshold = 2
+	if _, err := ckks1.LagrangeShare(forged, []ckks.ThresholdParty{forged.ThresholdParty, shares[1].ThresholdParty}); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("forged share with a lower threshold accepted")
+	}
+	if _, err := ckks1.LagrangeShare(shares[1], append(parties(2, 3), forged.ThresholdParty)); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("participant with a different threshold accepted")
+	}
+
+	// avec un premier q_0 = 17, les indices 1 et 18 sont égaux modulo q_0 : leur coefficient de Lagrange n'existe pas
+	small := mustCKKS(ckks.NewCKKS(4, 2, 0, 5, 5, s2))
+	smallShares, err := small.ShareSecretKey(small.SKeyGen(), 2, 3)
+	if err != nil || small.Moduli[0] != 17 {
+		t.Fatal(err, small.Moduli)
+	}
+	group := []ckks.ThresholdParty{smallShares[0].ThresholdParty, {Index: 18, Threshold: 2}}
+	if _, err := small.LagrangeShare(smallShares[0], group); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("undefined Lagrange coefficient accepted", err)
+	}
+	if _, err := small.ShareSecretKey(small.SKeyGen(), 2, 17); !errors.Is(err, ckks.ErrInvalidArgument) {
+		t.Error("more parties than the smallest prime accepted")
+	}
+}
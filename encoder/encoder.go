@@ -1,9 +1,9 @@
 package encoder
 
 import (
+	"fmt"
 	"math"
 	"math/big"
-	"math/cmplx"
 
 	"kazat.ch/lbcrypto/cMat"
 	"kazat.ch/lbcrypto/poly"
@@ -11,12 +11,13 @@ import (
 
 // Contitent les paramètres du schéma nécessaires à l'exécution des procédures d'encodag et décodage
 // Sert essentiellement à éviter l'utilisation de variables globales.
+// Le slot j correspond à l'évaluation en la racine xi^(5^j) où xi = exp(i*pi/N) : l'automorphisme
+// X -> X^(5^k) décale donc cycliquement les slots de k positions, et X -> X^-1 les conjugue
 type Encoder struct {
-	N     int        // double de la dimension des vecteurs à encoder
-	scale complex128 // mise à l'échelle effectuée durant l'encodage
-	ENC   cMat.CMat  // matrice d'encodage
-	DEC   cMat.CMat  // matrice de décodage
-	Basis cMat.CMat  // base pour l'espace sur lequel on projète durant l'encodage
+	N        int          // double de la dimension des vecteurs à encoder
+	scale    complex128   // mise à l'échelle effectuée durant l'encodage
+	rotGroup []int        // rotGroup[j] = 5^j mod 2N
+	ksiPows  []complex128 // ksiPows[k] = xi^k pour k = 0, ..., 2N
 }
 
 // Décrit un plaintext avec les infos nécessaires au décodage dans Scale
@@ -33,71 +34,65 @@ func NewPT(pol poly.Poly, scale complex128) PT {
 
 // renvoie un encoder complet sur la base du double de la dimension des vecteurs à encoder <N>
 // et de l'échelle de base faite durant l'encodage <scale>
-func NewEncoder(N int, scale complex128) Encoder {
-	if N%2 != 0 {
-		panic("Error : parameter N must be even")
+// ErrInvalidArgument si <N> n'est pas une puissance de 2 (au moins 2) ou si l'échelle n'est pas un réel positif fini
+func NewEncoder(N int, scale complex128) (Encoder, error) {
+	if N < 2 || N&(N-1) != 0 {
+		return Encoder{}, fmt.Errorf("%w : N must be a power of 2, got %d", ErrInvalidArgument, N)
 	}
-	exposant := complex(0, math.Pi/float64(N))
-	xi := cmplx.Exp(exposant)
-	v := make([]complex128, int(N*N))
-
-	// Création de la matrice de Vandermonde et de son inverse
-	// i.e. isomorphisme sigma et sigma inverse
-	for i := 0; i < N; i++ {
-		root := cmplx.Pow(xi, complex(float64(2*i)+1, 0))
-		for j := 0; j < N; j++ {
-			v[N*i+j] = cmplx.Pow(root, complex(float64(j), 0))
-		}
+	if !(real(scale) > 0) || math.IsInf(real(scale), 1) {
+		return Encoder{}, fmt.Errorf("%w : the scale must be positive and finite, got %g", ErrInvalidArgument, real(scale))
 	}
-	DEC := cMat.NewCMat(N, N, v)
-	R, S := DEC.Invert()
-	ENC := cMat.Merge(&R, &S)
-
-	// Image de la base canonique de l'ensemble des polynomes
-	// par sigma (pour projection des vecteurs)
-	var truc []complex128
-	data := make([]complex128, N)
-	for i := 0; i < N; i++ {
-		if i > 0 {
-			data[i-1] = 0
-		}
-		data[i] = 1
-		G := cMat.NewCMat(N, 1, data)
-		truc = append(truc, G.Mult(&DEC, &G).GetData()...)
+	M := 2 * N
+
+	rotGroup := make([]int, N/2)
+	pow := 1
+	for j := range rotGroup {
+		rotGroup[j] = pow
+		pow = (5 * pow) % M
+	}
+
+	ksiPows := make([]complex128, M+1)
+	for k := 0; k <= M; k++ {
+		angle := 2 * math.Pi * float64(k) / float64(M)
+		ksiPows[k] = complex(math.Cos(angle), math.Sin(angle))
 	}
-	basis := cMat.NewCMat(N, N, truc)
 
 	res := Encoder{
-		N:     N,
-		ENC:   ENC,
-		DEC:   DEC,
-		scale: scale,
-		Basis: basis,
+		N:        N,
+		scale:    scale,
+		rotGroup: rotGroup,
+		ksiPows:  ksiPows,
 	}
-	return res
+	return res, nil
 }
 
 // Renvoie un plaintext correspondant à l'encodage d'un vecteur <v> à l'aide du reciever <enc>
-func (enc *Encoder) Encode(v *cMat.CMat) PT {
+// les coefficients sont m_k = round(scale * 2/N * Re(sum_j v_j * xi^(-5^j * k))), calculés par FFT
+// ErrDimension si <v> n'a pas N/2 composantes
+func (enc *Encoder) Encode(v *cMat.CMat) (PT, error) {
+	if n := len(v.GetData()); n != enc.N/2 {
+		return PT{}, fmt.Errorf("%w : the encoder expects %d slots, got %d", ErrDimension, enc.N/2, n)
+	}
+	return enc.encode(v), nil
+}
+
+// comme Encode, pour un vecteur <v> de dimension N/2
+func (enc *Encoder) encode(v *cMat.CMat) PT {
 
 	N := enc.N
 	originaldata := v.GetData()
-	if len(originaldata) != N/2 {
-		panic("Error : message dimension does not match this encoder")
-	}
 
-	data := make([]complex128, N)
+	data := make([]complex128, N/2)
+	copy(data, originaldata)
+	enc.fftSpecialInv(data)
 
-	for i, val := range originaldata {
-		data[i] = val
-		data[i+N/2] = cmplx.Conj(originaldata[N/2-1-i])
+	newv := cMat.NewCMat(N, 1, make([]complex128, N))
+	coefs := newv.GetData()
+	for i, val := range data {
+		coefs[i] = complex(real(val), 0)
+		coefs[i+N/2] = complex(imag(val), 0)
 	}
-
-	newv := cMat.NewCMat(N, 1, data)
-
 	newv.Scale(enc.scale)
-	newv = *cMat.ProjectOnRaws(&newv, &enc.Basis)
-	newv.Mult(&enc.ENC, &newv)
 	pol := enc.ToPol(&newv)
 
 	res := PT{Pol: pol, Scale: enc.scale}
@@ -106,22 +101,95 @@ func (enc *Encoder) Encode(v *cMat.CMat) PT {
 }
 
 // Renvoie un vecteur correspondant au décodage d'un plaintext <pt> à l'aide du reciever <enc>
+// i.e. les évaluations du polynôme de <pt> aux racines xi^(5^j), divisées par l'échelle de <pt>
 func (enc *Encoder) Decode(pt PT) cMat.CMat {
 
-	v := enc.ToMat(pt.Pol)
-	v.Mult(&enc.DEC, &v)
-	v.Scale(1.0 / pt.Scale)
-	data := v.GetData()[0 : enc.N/2]
-	res := cMat.NewCMat(enc.N/2, 1, data)
+	N := enc.N
+	m := enc.ToMat(pt.Pol)
+	coefs := make([]complex128, N)
+	copy(coefs, m.GetData())
+
+	data := make([]complex128, N/2)
+	for i := range data {
+		data[i] = complex(real(coefs[i]), real(coefs[i+N/2]))
+	}
+	enc.fftSpecial(data)
+
+	res := cMat.NewCMat(N/2, 1, data)
+	res.Scale(1.0 / pt.Scale)
 	return res
 }
 
+// applique in place à <vals> la transformation m -> (m(xi^(5^j)))_j, où les coefficients
+// de m sont donnés par m_k + i*m_(k+N/2) = vals[k] (variante de la FFT adaptée à l'ordre des slots)
+func (enc *Encoder) fftSpecial(vals []complex128) {
+	n := len(vals)
+	M := 2 * enc.N
+	bitReverse(vals)
+	for length := 2; length <= n; length <<= 1 {
+		lenh, lenq := length>>1, length<<2
+		gap := M / lenq
+		for i := 0; i < n; i += length {
+			for j := 0; j < lenh; j++ {
+				idx := (enc.rotGroup[j] % lenq) * gap
+				u := vals[i+j]
+				v := vals[i+j+lenh] * enc.ksiPows[idx]
+				vals[i+j] = u + v
+				vals[i+j+lenh] = u - v
+			}
+		}
+	}
+}
+
+// applique in place à <vals> l'inverse de fftSpecial
+func (enc *Encoder) fftSpecialInv(vals []complex128) {
+	n := len(vals)
+	M := 2 * enc.N
+	for length := n; length >= 2; length >>= 1 {
+		lenh, lenq := length>>1, length<<2
+		gap := M / lenq
+		for i := 0; i < n; i += length {
+			for j := 0; j < lenh; j++ {
+				idx := (lenq - (enc.rotGroup[j] % lenq)) * gap
+				u := vals[i+j] + vals[i+j+lenh]
+				v := (vals[i+j] - vals[i+j+lenh]) * enc.ksiPows[idx]
+				vals[i+j] = u
+				vals[i+j+lenh] = v
+			}
+		}
+	}
+	bitReverse(vals)
+	for i := range vals {
+		vals[i] /= complex(float64(n), 0)
+	}
+}
+
+// permute in place les éléments de <vals> (de longueur une puissance de 2) selon l'ordre bit-reversed
+func bitReverse(vals []complex128) {
+	n := len(vals)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			vals[i], vals[j] = vals[j], vals[i]
+		}
+	}
+}
+
 // retourne l'objet de type poly.Poly dont les coefficiants sont donnés par le vecteur <m>
 func (enc *Encoder) ToPol(m *cMat.CMat) poly.Poly {
 	srcData := m.GetData()
 	dstData := make([]*big.Int, len(srcData))
 	for k, coef := range srcData {
-		dstData[k] = big.NewInt(int64(math.Round(real(coef))))
+		c := math.Round(real(coef))
+		if math.Abs(c) < 1<<62 {
+			dstData[k] = big.NewInt(int64(c))
+		} else { // grandes échelles
+			dstData[k], _ = big.NewFloat(c).Int(nil)
+		}
 	}
 	pol := poly.NewPoly(dstData)
 
@@ -153,6 +221,6 @@ func (enc *Encoder) ConstToPT(k float64) PT {
 	}
 
 	v := cMat.NewCMat(n/2, 1, data)
-	pt := enc.Encode(&v)
+	pt := enc.encode(&v)
 	return pt
 }
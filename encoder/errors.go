@@ -0,0 +1,14 @@
+package encoder
+
+import (
+	"kazat.ch/lbcrypto/cMat"
+	"kazat.ch/lbcrypto/poly"
+)
+
+// Erreurs retournées par les fonctions du package (éventuellement enveloppées avec fmt.Errorf et %w,
+// elles se testent avec errors.Is)
+var (
+	ErrDimension       = cMat.ErrDimension       // la même valeur que cMat.ErrDimension
+	ErrFormat          = poly.ErrFormat          // la même valeur que poly.ErrFormat
+	ErrInvalidArgument = poly.ErrInvalidArgument // la même valeur que poly.ErrInvalidArgument
+)
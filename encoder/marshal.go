@@ -0,0 +1,60 @@
+package encoder
+
+import (
+	"io"
+
+	"kazat.ch/lbcrypto/poly"
+)
+
+// Format d'un PT (voir poly.NewFrame) : l'échelle (parties réelle et imaginaire, 8 octets chacune)
+// suivie du cadre du polynôme (voir poly.Poly.MarshalBinary)
+
+// retourne la représentation binaire du reciever (voir le format ci-dessus)
+func (pt PT) MarshalBinary() ([]byte, error) {
+	pol, err := pt.Pol.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	w := poly.FrameWriter{}
+	w.Float64(real(pt.Scale))
+	w.Float64(imag(pt.Scale))
+	w.Bytes(pol)
+	return w.Frame(poly.TagPT), nil
+}
+
+// remplace le reciever par le plaintext représenté par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'un PT
+func (pt *PT) UnmarshalBinary(data []byte) error {
+	payload, err := poly.OpenFrame(data, poly.TagPT)
+	if err != nil {
+		return err
+	}
+	r := poly.NewFrameReader(payload)
+	scale := complex(r.Float64(), r.Float64())
+	frame := r.Frame()
+	if err := r.Close(); err != nil {
+		return err
+	}
+
+	var pol poly.Poly
+	if err := pol.UnmarshalBinary(frame); err != nil {
+		return err
+	}
+	*pt = PT{Pol: pol, Scale: scale}
+	return nil
+}
+
+// écrit la représentation binaire du reciever dans <w> (voir MarshalBinary)
+func (pt PT) WriteTo(w io.Writer) (int64, error) {
+	data, err := pt.MarshalBinary()
+	return poly.WriteFrame(w, data, err)
+}
+
+// lit dans <r> un plaintext écrit par WriteTo et le place dans le reciever
+func (pt *PT) ReadFrom(r io.Reader) (int64, error) {
+	data, n, err := poly.ReadFrame(r)
+	if err != nil {
+		return n, err
+	}
+	return n, pt.UnmarshalBinary(data)
+}
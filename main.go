@@ -49,71 +49,79 @@ func GetEncoder(vectLen, delta_nb_bits int) encoder.Encoder {
 
 	baseScale := complex(deltaFloat64, 0)
 
-	return encoder.NewEncoder(vectLen, baseScale)
+	enc, err := encoder.NewEncoder(vectLen, baseScale)
+	if err != nil {
+		panic(err)
+	}
+	return enc
 }
 
-// Returns a CKKS scheme with h = N/2 and s2 = 3.2
-// Should be defined otherwise to give security control to users
-func GetCKKS(N, delta_nb_bits, q0_nb_bits, nb_levels int) ckks.CKKS {
-
-	Q := binStrRep(q0_nb_bits + nb_levels*delta_nb_bits)
-	QL := new(big.Int)
-	QL.SetString(Q, 2)
-
-	P := QL
+// Returns a CKKS scheme for the given parameters, refusing parameters below their security level
+func GetCKKS(params ckks.Parameters) ckks.CKKS {
 
-	h := N / 2
-	s2 := 3.2
-
-	return ckks.NewCKKS(QL, P, N, h, nb_levels, s2)
+	ckks1, err := ckks.NewCKKSFromParameters(params)
+	if err != nil {
+		panic(err)
+	}
+	return ckks1
 }
 
 func main() {
 
 	// Situation defined parameters
-	nbStudents := 16 // includes fake students when not a power of 2
+	nbStudents := 16 // real students, the other slots are filled with fake students
 	nbCourses := 10
 
+	// Setting parameters for CKKS on client side : 128-bit security, 5 levels of 40 bits
+	// N/2 = 8192 slots, one per (real or fake) student
+	params := ckks.PN14QP438
+	N := 1 << params.LogN
+	delta_nb_bits := params.LogDelta
+
 	// Generating data based on the above parameters
 	// vectors[i] will contains the grades for the course #i
 	vectors := make([]cMat.CMat, nbCourses)
 	for i := 0; i < nbCourses; i++ {
-		vectors[i] = cMat.NewCMat(nbStudents, 1, randGradesVect6(nbStudents))
+		vectors[i] = cMat.NewCMat(N/2, 1, randGradesVect6(N/2))
 	}
 
-	// Setting parameters for CKKS on client side
-	N := 2 * nbStudents // fake students can be added for security and getting a power of 2
-	delta_nb_bits := 20
-	q0_nb_bits := 100
-	nb_levels := 10
-
 	// Generating encoder and CKKS instance based on the above parameters on client side
-	delta := GetDelta(delta_nb_bits) // used for the RS procedure in Var
 	enc := GetEncoder(N, delta_nb_bits)
-	ckks1 := GetCKKS(N, delta_nb_bits, q0_nb_bits, nb_levels)
+	ckks1 := GetCKKS(params)
+	info := ckks1.KeySwitchingInfo()
+	fmt.Printf("key switching : dnum = %d, log QP = %d, key size = %d bytes, security = %d bits \n", info.Dnum, info.LogQP, info.KeySize, ckks1.EstimatedSecurity())
 
 	// Generating keys on client side
 	sk := ckks1.SKeyGen()
-	pk := ckks1.PKeyGen(sk)
 	evk := ckks1.EvKeyGen(sk)
 
 	// Encoding
 	// pts[i] will contain the plaintext corresponding to the grades of course #i
 	pts := make([]encoder.PT, nbCourses)
 	for i := 0; i < nbCourses; i++ {
-		pts[i] = enc.Encode(&vectors[i])
+		pt, err := enc.Encode(&vectors[i])
+		if err != nil {
+			panic(err)
+		}
+		pts[i] = pt
 	}
 
 	// Encryption
 	// cts[i] will contain the plaintext corresponding to the grades of course #i
 	cts := make([]ckks.CT, nbCourses)
 	for i := 0; i < nbCourses; i++ {
-		cts[i] = ckks1.Encrypt(pts[i], pk)
+		cts[i] = ckks1.EncryptSK(pts[i], sk) // the client holds sk : A could be sent as its seed
 	}
 
 	// Computing mean and var on ciphertexts on server side
-	meanCT := ckks1.Mean(cts, pk, evk)
-	varCT := ckks1.Var(cts, pk, evk, delta)
+	meanCT, err := ckks1.Mean(cts)
+	if err != nil {
+		panic(err)
+	}
+	varCT, err := ckks1.Var(cts, evk)
+	if err != nil {
+		panic(err)
+	}
 
 	// Decryption and decoding on client side
 	meanPT := ckks1.Decrypt(meanCT, sk)
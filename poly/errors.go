@@ -0,0 +1,11 @@
+package poly
+
+import "errors"
+
+// Erreurs retournées par les fonctions du package (éventuellement enveloppées avec fmt.Errorf et %w,
+// elles se testent avec errors.Is)
+var (
+	ErrDivisionByZero  = errors.New("division by the zero polynomial")
+	ErrFormat          = errors.New("invalid binary format")
+	ErrInvalidArgument = errors.New("invalid argument")
+)
@@ -0,0 +1,430 @@
+package poly
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// Format binaire des objets de la bibliothèque (polynômes, plaintexts, ciphertexts et clés)
+// Chaque objet est écrit dans un cadre : un en-tête de FrameHeaderSize octets (les octets "LB", la version
+// du format, le type de l'objet, puis la taille du contenu sur 8 octets) suivi du contenu.
+// Les entiers sont en little-endian. Un objet composé contient les cadres de ses composantes : un cadre
+// peut donc toujours être lu d'un flux sans connaître son contenu (voir ReadFrame)
+
+// version du format écrit par MarshalBinary ; les cadres des versions précédentes restent lisibles,
+// ceux des versions suivantes sont rejetés
+// version 2 : les polynômes uniformes des clés peuvent être remplacés par leur graine (TagSeed) ; la version fixe
+// le tirage d'un polynôme depuis sa graine (flux random.PRNG, puis random.RandomRNSPolFrom, voir ckks)
+const FormatVersion = 2
+
+// taille en octets de l'en-tête d'un cadre
+const FrameHeaderSize = 12
+
+// Types des objets, écrits dans l'en-tête de leur cadre
+// ceux des autres packages sont réservés ici pour rester distincts
+const (
+	TagPoly         byte = iota + 1 // poly.Poly
+	TagRNSPoly                      // poly.RNSPoly
+	TagPT                           // encoder.PT
+	TagCT                           // ckks.CT
+	TagSecretKey                    // ckks.SecretKey
+	TagPublicKey                    // ckks.PublicKey
+	TagSwitchingKey                 // ckks.SwitchingKey
+	TagRotationKeys                 // ckks.RotationKeys
+	TagSeed                         // graine d'un poly.RNSPoly uniforme, à la place de ses coefficients (voir ckks)
+)
+
+// retourne le cadre de type <tag> dont le contenu est <payload>
+func NewFrame(tag byte, payload []byte) []byte {
+	frame := make([]byte, FrameHeaderSize, FrameHeaderSize+len(payload))
+	frame[0], frame[1], frame[2], frame[3] = 'L', 'B', FormatVersion, tag
+	binary.LittleEndian.PutUint64(frame[4:], uint64(len(payload)))
+	return append(frame, payload...)
+}
+
+// retourne le type du cadre commençant <data> et la taille du cadre complet
+// ErrFormat si l'en-tête est invalide ou d'une version ultérieure
+func FrameInfo(data []byte) (byte, int, error) {
+	if len(data) < FrameHeaderSize || data[0] != 'L' || data[1] != 'B' {
+		return 0, 0, fmt.Errorf("%w : missing frame header", ErrFormat)
+	}
+	if data[2] < 1 || data[2] > FormatVersion {
+		return 0, 0, fmt.Errorf("%w : unsupported version %d", ErrFormat, data[2])
+	}
+	size := binary.LittleEndian.Uint64(data[4:])
+	if size > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("%w : frame of %d bytes", ErrFormat, size)
+	}
+	return data[3], FrameHeaderSize + int(size), nil
+}
+
+// retourne le contenu du cadre <data>, qui doit être de type <tag> et n'être suivi d'aucun octet
+func OpenFrame(data []byte, tag byte) ([]byte, error) {
+	t, size, err := FrameInfo(data)
+	if err != nil {
+		return nil, err
+	}
+	if t != tag {
+		return nil, fmt.Errorf("%w : expected an object of type %d, got %d", ErrFormat, tag, t)
+	}
+	if size != len(data) {
+		return nil, fmt.Errorf("%w : frame of %d bytes, got %d", ErrFormat, size, len(data))
+	}
+	return data[FrameHeaderSize:], nil
+}
+
+// lit un cadre complet (en-tête compris) dans <r>, sans en interpréter le contenu
+// retourne aussi le nombre d'octets lus, pour les méthodes ReadFrom
+// le contenu est lu au fur et à mesure : la mémoire allouée suit les octets effectivement reçus,
+// et non la taille annoncée par l'en-tête
+func ReadFrame(r io.Reader) ([]byte, int64, error) {
+	header := make([]byte, FrameHeaderSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, int64(n), err
+	}
+	_, size, err := FrameInfo(header)
+	if err != nil {
+		return nil, int64(n), err
+	}
+	var frame bytes.Buffer
+	frame.Write(header)
+	m, err := io.CopyN(&frame, r, int64(size-FrameHeaderSize))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return frame.Bytes(), int64(n) + m, err
+}
+
+// Écrit le contenu d'un cadre
+type FrameWriter struct {
+	Buf []byte
+}
+
+func (w *FrameWriter) Byte(x byte) {
+	w.Buf = append(w.Buf, x)
+}
+
+func (w *FrameWriter) Uint32(x uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], x)
+	w.Buf = append(w.Buf, b[:]...)
+}
+
+func (w *FrameWriter) Uint64(x uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	w.Buf = append(w.Buf, b[:]...)
+}
+
+func (w *FrameWriter) Float64(x float64) {
+	w.Uint64(math.Float64bits(x))
+}
+
+// écrit les octets <b> tels quels (leur nombre doit être connu du lecteur)
+func (w *FrameWriter) Bytes(b []byte) {
+	w.Buf = append(w.Buf, b...)
+}
+
+// retourne le cadre de type <tag> contenant ce qui a été écrit
+func (w *FrameWriter) Frame(tag byte) []byte {
+	return NewFrame(tag, w.Buf)
+}
+
+// Lit le contenu d'un cadre ; après la première lecture impossible, les lectures retournent 0
+// et Close retourne l'erreur
+type FrameReader struct {
+	data []byte
+	err  error
+}
+
+// retourne un lecteur du contenu <payload>
+func NewFrameReader(payload []byte) *FrameReader {
+	return &FrameReader{data: payload}
+}
+
+// retourne les <n> octets suivants, sans les copier
+func (r *FrameReader) Bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = fmt.Errorf("%w : truncated data", ErrFormat)
+		return nil
+	}
+	res := r.data[:n]
+	r.data = r.data[n:]
+	return res
+}
+
+func (r *FrameReader) Byte() byte {
+	if b := r.Bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *FrameReader) Uint32() uint32 {
+	if b := r.Bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *FrameReader) Uint64() uint64 {
+	if b := r.Bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *FrameReader) Float64() float64 {
+	return math.Float64frombits(r.Uint64())
+}
+
+// retourne le cadre complet suivant, sans le copier
+func (r *FrameReader) Frame() []byte {
+	if r.err != nil {
+		return nil
+	}
+	_, size, err := FrameInfo(r.data)
+	if err != nil {
+		r.err = err
+		return nil
+	}
+	return r.Bytes(size)
+}
+
+// retourne la première erreur de lecture
+func (r *FrameReader) Err() error {
+	return r.err
+}
+
+// retourne le nombre d'octets restant à lire
+func (r *FrameReader) Len() int {
+	return len(r.data)
+}
+
+// retourne la première erreur de lecture, ou ErrFormat s'il reste des octets non lus
+func (r *FrameReader) Close() error {
+	if r.err == nil && len(r.data) != 0 {
+		r.err = fmt.Errorf("%w : %d trailing bytes", ErrFormat, len(r.data))
+	}
+	return r.err
+}
+
+// écrit <data> dans <w> ; utilisé par les méthodes WriteTo
+func WriteFrame(w io.Writer, data []byte, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Format d'un Poly : le nombre de coefficients et la taille w en octets du plus grand en valeur absolue (4 octets chacun),
+// puis chaque coefficient sous la forme d'un octet de signe et de sa valeur absolue sur w octets (big-endian)
+
+// retourne la représentation binaire du reciever (voir le format ci-dessus)
+// ErrInvalidArgument si un coefficient est nil
+func (pol Poly) MarshalBinary() ([]byte, error) {
+	width := 0
+	for i, c := range pol.Coefs {
+		if c == nil {
+			return nil, fmt.Errorf("%w : nil coefficient of degree %d", ErrInvalidArgument, i)
+		}
+		if n := (c.BitLen() + 7) / 8; n > width {
+			width = n
+		}
+	}
+
+	w := FrameWriter{Buf: make([]byte, 0, 8+len(pol.Coefs)*(1+width))}
+	w.Uint32(uint32(len(pol.Coefs)))
+	w.Uint32(uint32(width))
+	abs := make([]byte, width)
+	for _, c := range pol.Coefs {
+		if c.Sign() < 0 {
+			w.Byte(1)
+		} else {
+			w.Byte(0)
+		}
+		w.Bytes(c.FillBytes(abs))
+	}
+	return w.Frame(TagPoly), nil
+}
+
+// remplace le reciever par le polynôme représenté par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'un Poly
+func (pol *Poly) UnmarshalBinary(data []byte) error {
+	payload, err := OpenFrame(data, TagPoly)
+	if err != nil {
+		return err
+	}
+	r := NewFrameReader(payload)
+	n, width := int64(r.Uint32()), int64(r.Uint32())
+	if r.err == nil && n*(1+width) != int64(r.Len()) {
+		return fmt.Errorf("%w : %d coefficients of %d bytes in %d bytes", ErrFormat, n, width, r.Len())
+	}
+
+	coefs := make([]*big.Int, n)
+	for i := range coefs {
+		sign := r.Byte()
+		coefs[i] = new(big.Int).SetBytes(r.Bytes(int(width)))
+		if sign == 1 {
+			coefs[i].Neg(coefs[i])
+		} else if sign != 0 {
+			return fmt.Errorf("%w : invalid sign byte %d", ErrFormat, sign)
+		}
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	pol.Coefs = coefs
+	return nil
+}
+
+// écrit la représentation binaire du reciever dans <w> (voir MarshalBinary)
+func (pol Poly) WriteTo(w io.Writer) (int64, error) {
+	data, err := pol.MarshalBinary()
+	return WriteFrame(w, data, err)
+}
+
+// lit dans <r> un polynôme écrit par WriteTo et le place dans le reciever
+func (pol *Poly) ReadFrom(r io.Reader) (int64, error) {
+	data, n, err := ReadFrame(r)
+	if err != nil {
+		return n, err
+	}
+	return n, pol.UnmarshalBinary(data)
+}
+
+// Format d'un RNSPoly : N et le nombre de limbs (4 octets chacun), un octet valant 1 en forme NTT,
+// les premiers de la base (8 octets chacun), puis les limbs : les coefficients modulo q_i ont une taille fixe,
+// le nombre d'octets de q_i
+
+// retourne le nombre d'octets d'un coefficient modulo <q>
+func coefSize(q uint64) int {
+	return (bits.Len64(q) + 7) / 8
+}
+
+// retourne la représentation binaire du reciever (voir le format ci-dessus)
+// ErrInvalidArgument si le polynôme est vide, ErrFormat si ses limbs ne correspondent pas à ses modules
+func (pol RNSPoly) MarshalBinary() ([]byte, error) {
+	if len(pol.Coefs) == 0 || len(pol.Coefs[0]) == 0 {
+		return nil, fmt.Errorf("%w : empty RNS polynomial", ErrInvalidArgument)
+	}
+	N := len(pol.Coefs[0])
+	if len(pol.Coefs) != len(pol.Moduli) {
+		return nil, fmt.Errorf("%w : %d limbs for %d moduli", ErrFormat, len(pol.Coefs), len(pol.Moduli))
+	}
+
+	size := 9 + 8*len(pol.Moduli)
+	for _, q := range pol.Moduli {
+		size += N * coefSize(q)
+	}
+	w := FrameWriter{Buf: make([]byte, 0, size)}
+	w.Uint32(uint32(N))
+	w.Uint32(uint32(len(pol.Moduli)))
+	if pol.IsNTT {
+		w.Byte(1)
+	} else {
+		w.Byte(0)
+	}
+	for _, q := range pol.Moduli {
+		w.Uint64(q)
+	}
+
+	var buf [8]byte
+	for i, q := range pol.Moduli {
+		width := coefSize(q)
+		if len(pol.Coefs[i]) != N {
+			return nil, fmt.Errorf("%w : limbs of different lengths", ErrFormat)
+		}
+		for _, c := range pol.Coefs[i] {
+			binary.LittleEndian.PutUint64(buf[:], c)
+			w.Bytes(buf[:width])
+		}
+	}
+	return w.Frame(TagRNSPoly), nil
+}
+
+// remplace le reciever par le polynôme représenté par <data> (voir MarshalBinary)
+// ErrFormat si <data> n'est pas la représentation d'un RNSPoly, si N n'est pas une puissance de 2, s'il n'y a
+// aucun limb, si un module n'est pas un premier congru à 1 mod 2N ou si un coefficient n'est pas réduit
+// les tailles lues sont vérifiées avant d'allouer les coefficients
+func (pol *RNSPoly) UnmarshalBinary(data []byte) error {
+	payload, err := OpenFrame(data, TagRNSPoly)
+	if err != nil {
+		return err
+	}
+	r := NewFrameReader(payload)
+	N, nbLimbs := int(r.Uint32()), int(r.Uint32())
+	ntt := r.Byte()
+	if r.err != nil {
+		return r.err
+	}
+	if ntt > 1 || nbLimbs == 0 || N == 0 || N&(N-1) != 0 || int64(nbLimbs)*8 > int64(r.Len()) {
+		return fmt.Errorf("%w : invalid RNS polynomial header", ErrFormat)
+	}
+	moduli := make([]uint64, nbLimbs)
+	size := int64(0)
+	for i := range moduli {
+		moduli[i] = r.Uint64()
+		if r.err != nil {
+			break
+		}
+		// premiers NTT-friendly, comme ceux de GeneratePrimes (ProbablyPrime est exact sur 64 bits)
+		if moduli[i]%uint64(2*N) != 1 || !new(big.Int).SetUint64(moduli[i]).ProbablyPrime(0) {
+			return fmt.Errorf("%w : invalid modulus %d", ErrFormat, moduli[i])
+		}
+		size += int64(N) * int64(coefSize(moduli[i]))
+		if size > int64(r.Len()) {
+			break // rejeté ci-dessous, avant toute allocation des coefficients
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if size != int64(r.Len()) {
+		return fmt.Errorf("%w : %d limbs of degree %d in %d bytes", ErrFormat, nbLimbs, N, r.Len())
+	}
+
+	coefs := make([][]uint64, nbLimbs)
+	for i, q := range moduli {
+		width := coefSize(q)
+		var buf [8]byte // les octets au-delà de width restent nuls
+		coefs[i] = make([]uint64, N)
+		for j := range coefs[i] {
+			copy(buf[:], r.Bytes(width))
+			coefs[i][j] = binary.LittleEndian.Uint64(buf[:])
+			if coefs[i][j] >= q {
+				return fmt.Errorf("%w : coefficient not reduced modulo %d", ErrFormat, q)
+			}
+		}
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	*pol = RNSPoly{Coefs: coefs, Moduli: moduli, IsNTT: ntt == 1}
+	return nil
+}
+
+// écrit la représentation binaire du reciever dans <w> (voir MarshalBinary)
+func (pol RNSPoly) WriteTo(w io.Writer) (int64, error) {
+	data, err := pol.MarshalBinary()
+	return WriteFrame(w, data, err)
+}
+
+// lit dans <r> un polynôme écrit par WriteTo et le place dans le reciever
+func (pol *RNSPoly) ReadFrom(r io.Reader) (int64, error) {
+	data, n, err := ReadFrame(r)
+	if err != nil {
+		return n, err
+	}
+	return n, pol.UnmarshalBinary(data)
+}
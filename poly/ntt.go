@@ -0,0 +1,190 @@
+package poly
+
+import (
+	"math/big"
+	"math/bits"
+	"sync"
+)
+
+// Contient les puissances de la racine primitive 2N-ième psi de l'unité modulo q
+// nécessaires à la NTT négacyclique dans Z_q[X]/(X^N + 1)
+type nttTable struct {
+	N          int
+	q          uint64
+	psiRev     []uint64 // psi^bitrev(k)
+	psiRevS    []uint64 // constantes de Shoup associées
+	psiInvRev  []uint64 // psi^-bitrev(k)
+	psiInvRevS []uint64
+	nInv       uint64 // N^-1 mod q
+	nInvS      uint64
+}
+
+// les tables sont calculées une seule fois par couple (N, q)
+var (
+	nttTables      = make(map[[2]uint64]*nttTable)
+	nttTablesMutex sync.Mutex
+)
+
+// retourne la table NTT associée au couple (<N>, <q>), en la calculant si nécessaire
+func getNTTTable(N int, q uint64) *nttTable {
+	nttTablesMutex.Lock()
+	defer nttTablesMutex.Unlock()
+	key := [2]uint64{uint64(N), q}
+	if table, ok := nttTables[key]; ok {
+		return table
+	}
+	table := newNTTTable(N, q)
+	nttTables[key] = table
+	return table
+}
+
+// retourne la table NTT pour <N> une puissance de 2 et <q> un premier congru à 1 modulo 2N
+func newNTTTable(N int, q uint64) *nttTable {
+	if N&(N-1) != 0 {
+		panic("Error : N must be a power of 2 for the NTT")
+	}
+	if (q-1)%uint64(2*N) != 0 {
+		panic("Error : modulus is not NTT-friendly (q != 1 mod 2N)")
+	}
+
+	psi := primitiveRoot(N, q)
+	psiInv := new(big.Int).ModInverse(new(big.Int).SetUint64(psi), new(big.Int).SetUint64(q)).Uint64()
+
+	logN := bits.Len(uint(N)) - 1
+	table := &nttTable{
+		N:          N,
+		q:          q,
+		psiRev:     make([]uint64, N),
+		psiRevS:    make([]uint64, N),
+		psiInvRev:  make([]uint64, N),
+		psiInvRevS: make([]uint64, N),
+	}
+	pow, powInv := uint64(1), uint64(1)
+	for k := 0; k < N; k++ {
+		r := bitReverse(k, logN)
+		table.psiRev[r] = pow
+		table.psiInvRev[r] = powInv
+		pow = mulMod(pow, psi, q)
+		powInv = mulMod(powInv, psiInv, q)
+	}
+	for k := 0; k < N; k++ {
+		table.psiRevS[k] = shoupConst(table.psiRev[k], q)
+		table.psiInvRevS[k] = shoupConst(table.psiInvRev[k], q)
+	}
+	table.nInv = new(big.Int).ModInverse(big.NewInt(int64(N)), new(big.Int).SetUint64(q)).Uint64()
+	table.nInvS = shoupConst(table.nInv, q)
+	return table
+}
+
+// retourne une racine primitive 2N-ième de l'unité modulo le premier <q>
+func primitiveRoot(N int, q uint64) uint64 {
+	exp := (q - 1) / uint64(2*N)
+	for g := uint64(2); g < q; g++ {
+		psi := powMod(g, exp, q)
+		if powMod(psi, uint64(N), q) == q-1 { // psi^N = -1 : psi est d'ordre exactement 2N
+			return psi
+		}
+	}
+	panic("Error : no primitive root found")
+}
+
+// retourne les <nbBits> bits de poids faible de <k> dans l'ordre inverse
+func bitReverse(k, nbBits int) int {
+	return int(bits.Reverse64(uint64(k)) >> (64 - nbBits))
+}
+
+// retourne floor(w * 2^64 / q), qui permet de calculer x*w mod q sans division (Shoup)
+func shoupConst(w, q uint64) uint64 {
+	quo, _ := bits.Div64(w, 0, q)
+	return quo
+}
+
+// retourne x * w mod q, où wS = shoupConst(w, q)
+func mulModShoup(x, w, wS, q uint64) uint64 {
+	hi, _ := bits.Mul64(x, wS)
+	r := x*w - hi*q
+	if r >= q {
+		r -= q
+	}
+	return r
+}
+
+// retourne a^e mod q
+func powMod(a, e, q uint64) uint64 {
+	res := uint64(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			res = mulMod(res, a, q)
+		}
+		a = mulMod(a, a, q)
+	}
+	return res
+}
+
+// applique in place la NTT négacyclique au limb <a> (Cooley-Tukey, sortie en ordre bit-reversed)
+func (table *nttTable) forward(a []uint64) {
+	N, q := table.N, table.q
+	t := N
+	for m := 1; m < N; m <<= 1 {
+		t >>= 1
+		for i := 0; i < m; i++ {
+			j1 := 2 * i * t
+			S, SS := table.psiRev[m+i], table.psiRevS[m+i]
+			for j := j1; j < j1+t; j++ {
+				U := a[j]
+				V := mulModShoup(a[j+t], S, SS, q)
+				a[j] = addMod(U, V, q)
+				a[j+t] = subMod(U, V, q)
+			}
+		}
+	}
+}
+
+// applique in place la NTT inverse au limb <a> (Gentleman-Sande, entrée en ordre bit-reversed)
+func (table *nttTable) inverse(a []uint64) {
+	N, q := table.N, table.q
+	t := 1
+	for m := N; m > 1; m >>= 1 {
+		j1 := 0
+		h := m >> 1
+		for i := 0; i < h; i++ {
+			S, SS := table.psiInvRev[h+i], table.psiInvRevS[h+i]
+			for j := j1; j < j1+t; j++ {
+				U, V := a[j], a[j+t]
+				a[j] = addMod(U, V, q)
+				a[j+t] = mulModShoup(subMod(U, V, q), S, SS, q)
+			}
+			j1 += 2 * t
+		}
+		t <<= 1
+	}
+	for j := range a {
+		a[j] = mulModShoup(a[j], table.nInv, table.nInvS, q)
+	}
+}
+
+// retourne la forme NTT du polynôme <pol> (une copie de <pol> s'il y est déjà)
+func NTT(pol RNSPoly) RNSPoly {
+	res := CopyRNS(pol)
+	if pol.IsNTT {
+		return res
+	}
+	for i, q := range res.Moduli {
+		getNTTTable(res.N(), q).forward(res.Coefs[i])
+	}
+	res.IsNTT = true
+	return res
+}
+
+// retourne la forme coefficients du polynôme <pol> (une copie de <pol> s'il y est déjà)
+func INTT(pol RNSPoly) RNSPoly {
+	res := CopyRNS(pol)
+	if !pol.IsNTT {
+		return res
+	}
+	for i, q := range res.Moduli {
+		getNTTTable(res.N(), q).inverse(res.Coefs[i])
+	}
+	res.IsNTT = false
+	return res
+}
@@ -132,11 +132,14 @@ func Add(u, v Poly) Poly {
 }
 
 // retourne le quotient et le reste de la division du poly.Poly <u> par le poly.Poly <v>
-//AJTOUER GESTION DES ERREUR SUR DIVISION PAR POLYNOME NUL
-func PolyDiv(u, v Poly) (Poly, Poly) {
+// ErrDivisionByZero si <v> est le polynôme nul
+func PolyDiv(u, v Poly) (Poly, Poly, error) {
 
+	if len(v.Coefs) == 0 || v.Coefs[v.Degree()].Sign() == 0 {
+		return Poly{}, Poly{}, ErrDivisionByZero
+	}
 	if u.Degree() < v.Degree() {
-		return NewPoly([]*big.Int{big.NewInt(0)}), u
+		return NewPoly([]*big.Int{big.NewInt(0)}), u, nil
 	} else {
 		newu := Copy(u)
 		degNewu := newu.Degree()
@@ -159,7 +162,7 @@ func PolyDiv(u, v Poly) (Poly, Poly) {
 			i++
 
 		}
-		return NewPoly(quotient), newu
+		return NewPoly(quotient), newu, nil
 	}
 
 }
@@ -205,9 +208,13 @@ func (pol *Poly) TakeCoefMod(mod *big.Int) *Poly {
 }
 
 //Retourne le reste de la division du produit polynomial (<u>*<v>) par le polynôme <mod>
-func MultMod(u, v, mod Poly) Poly {
+// ErrDivisionByZero si <mod> est le polynôme nul
+func MultMod(u, v, mod Poly) (Poly, error) {
 	pro := Mult(u, v)
-	_, pro = PolyDiv(pro, mod)
+	_, pro, err := PolyDiv(pro, mod)
+	if err != nil {
+		return Poly{}, err
+	}
 	pro.Deflate()
-	return pro
+	return pro, nil
 }
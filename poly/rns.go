@@ -0,0 +1,432 @@
+package poly
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// Représente un polynôme de Z_Q[X]/(X^N + 1) en RNS, avec Q = q_0 * ... * q_l
+// Coefs[i][j] est le coefficient de X^j modulo le premier Moduli[i]
+// En forme NTT, Coefs[i] contient les évaluations du polynôme aux racines 2N-ièmes primitives de l'unité modulo Moduli[i]
+type RNSPoly struct {
+	Coefs  [][]uint64 // un limb par premier de la base
+	Moduli []uint64   // base RNS q_0, ..., q_l (premiers tenant sur un mot machine, congrus à 1 mod 2N)
+	IsNTT  bool       // vrai si les limbs sont en forme NTT
+}
+
+// retourne le polynôme nul de degré < <N> dans la base RNS <moduli>
+func NewRNSPoly(N int, moduli []uint64) RNSPoly {
+	coefs := make([][]uint64, len(moduli))
+	for i := range coefs {
+		coefs[i] = make([]uint64, N)
+	}
+	return RNSPoly{Coefs: coefs, Moduli: moduli}
+}
+
+// retourne une copie du polynôme <pol>
+func CopyRNS(pol RNSPoly) RNSPoly {
+	coefs := make([][]uint64, len(pol.Coefs))
+	for i := range pol.Coefs {
+		coefs[i] = make([]uint64, len(pol.Coefs[i]))
+		copy(coefs[i], pol.Coefs[i])
+	}
+	return RNSPoly{Coefs: coefs, Moduli: pol.Moduli, IsNTT: pol.IsNTT}
+}
+
+// retourne le degré N du polynôme X^N + 1 définissant l'anneau du reciever
+func (pol *RNSPoly) N() int {
+	return len(pol.Coefs[0])
+}
+
+// retourne le niveau du reciever, i.e. le nombre de premiers de sa base moins un
+func (pol *RNSPoly) Level() int {
+	return len(pol.Moduli) - 1
+}
+
+// retourne le module Q = q_0 * ... * q_l du reciever
+func (pol *RNSPoly) Modulus() *big.Int {
+	return ProdModuli(pol.Moduli)
+}
+
+// retourne le polynôme <pol> restreint aux <level>+1 premiers de sa base
+// les limbs ne sont pas copiés
+func DropLimbs(pol RNSPoly, level int) RNSPoly {
+	return RNSPoly{Coefs: pol.Coefs[:level+1], Moduli: pol.Moduli[:level+1], IsNTT: pol.IsNTT}
+}
+
+// retourne le polynôme dont la base est la concaténation des bases de <u> et <v>
+// les limbs ne sont pas copiés (sauf ceux de <v> s'il doit être mis dans la forme de <u>, voir sameForm)
+func ConcatRNS(u, v RNSPoly) RNSPoly {
+	v = sameForm(u, v)
+	coefs := make([][]uint64, 0, len(u.Coefs)+len(v.Coefs))
+	coefs = append(append(coefs, u.Coefs...), v.Coefs...)
+	moduli := make([]uint64, 0, len(u.Moduli)+len(v.Moduli))
+	moduli = append(append(moduli, u.Moduli...), v.Moduli...)
+	return RNSPoly{Coefs: coefs, Moduli: moduli, IsNTT: u.IsNTT}
+}
+
+// retourne le produit des premiers <moduli>
+func ProdModuli(moduli []uint64) *big.Int {
+	res := big.NewInt(1)
+	for _, q := range moduli {
+		res.Mul(res, new(big.Int).SetUint64(q))
+	}
+	return res
+}
+
+// retourne <v> dans la forme (NTT ou coefficients) de <u> : les opérations sur deux polynômes acceptent
+// des opérandes de formes différentes, le résultat étant dans la forme du premier
+func sameForm(u, v RNSPoly) RNSPoly {
+	switch {
+	case u.IsNTT == v.IsNTT:
+		return v
+	case u.IsNTT:
+		return NTT(v)
+	default:
+		return INTT(v)
+	}
+}
+
+// retourne le nombre de limbs communs à <u> et <v>
+func commonLimbs(u, v RNSPoly) int {
+	if len(u.Coefs) < len(v.Coefs) {
+		return len(u.Coefs)
+	}
+	return len(v.Coefs)
+}
+
+// retourne la somme des polynômes <u> et <v> sur la base commune (la plus courte)
+func AddRNS(u, v RNSPoly) RNSPoly {
+	v = sameForm(u, v)
+	n := commonLimbs(u, v)
+	res := NewRNSPoly(u.N(), u.Moduli[:n])
+	res.IsNTT = u.IsNTT
+	for i := 0; i < n; i++ {
+		q := u.Moduli[i]
+		for j, c := range u.Coefs[i] {
+			res.Coefs[i][j] = addMod(c, v.Coefs[i][j], q)
+		}
+	}
+	return res
+}
+
+// retourne la différence <u> - <v> sur la base commune (la plus courte)
+func SubRNS(u, v RNSPoly) RNSPoly {
+	v = sameForm(u, v)
+	n := commonLimbs(u, v)
+	res := NewRNSPoly(u.N(), u.Moduli[:n])
+	res.IsNTT = u.IsNTT
+	for i := 0; i < n; i++ {
+		q := u.Moduli[i]
+		for j, c := range u.Coefs[i] {
+			res.Coefs[i][j] = subMod(c, v.Coefs[i][j], q)
+		}
+	}
+	return res
+}
+
+// retourne l'opposé du polynôme <pol>
+func NegRNS(pol RNSPoly) RNSPoly {
+	res := NewRNSPoly(pol.N(), pol.Moduli)
+	res.IsNTT = pol.IsNTT
+	for i, q := range pol.Moduli {
+		for j, c := range pol.Coefs[i] {
+			res.Coefs[i][j] = subMod(0, c, q)
+		}
+	}
+	return res
+}
+
+// retourne le produit du polynôme <pol> par l'entier <k> (éventuellement négatif)
+func ScaleRNS(pol RNSPoly, k *big.Int) RNSPoly {
+	res := NewRNSPoly(pol.N(), pol.Moduli)
+	res.IsNTT = pol.IsNTT
+	kMod := new(big.Int)
+	for i, q := range pol.Moduli {
+		kq := kMod.Mod(k, new(big.Int).SetUint64(q)).Uint64()
+		for j, c := range pol.Coefs[i] {
+			res.Coefs[i][j] = mulMod(c, kq, q)
+		}
+	}
+	return res
+}
+
+// retourne le produit des polynômes <u> et <v> modulo X^N + 1, limb par limb
+// si <u> et <v> sont en forme NTT, le produit est calculé coefficient par coefficient et reste en forme NTT,
+// sinon ils sont transformés, multipliés, et le produit est ramené en forme coefficients
+// si un seul est en forme NTT, l'autre y est mis et le produit reste en forme NTT
+func MultModRNS(u, v RNSPoly) RNSPoly {
+	if !u.IsNTT && !v.IsNTT {
+		return INTT(MultModRNS(NTT(u), NTT(v)))
+	}
+	if u.IsNTT {
+		v = sameForm(u, v)
+	} else {
+		u = sameForm(v, u)
+	}
+	n := commonLimbs(u, v)
+	res := NewRNSPoly(u.N(), u.Moduli[:n])
+	res.IsNTT = true
+	for i := 0; i < n; i++ {
+		q := u.Moduli[i]
+		a, b, c := u.Coefs[i], v.Coefs[i], res.Coefs[i]
+		for j := range c {
+			c[j] = mulMod(a[j], b[j], q)
+		}
+	}
+	return res
+}
+
+// retourne l'image du polynôme <pol> par l'automorphisme X -> X^<g>, pour <g> impair
+// en forme NTT, l'automorphisme permute les évaluations : pol(psi^e) -> pol(psi^(g*e))
+func Automorphism(pol RNSPoly, g int) RNSPoly {
+	N := pol.N()
+	M := 2 * N
+	g = ((g % M) + M) % M
+	res := NewRNSPoly(N, pol.Moduli)
+	res.IsNTT = pol.IsNTT
+
+	if pol.IsNTT {
+		logN := bits.Len(uint(N)) - 1
+		index := make([]int, N)
+		for k := range index {
+			e := 2*bitReverse(k, logN) + 1 // la kème évaluation est en psi^e
+			index[k] = bitReverse((e*g%M-1)/2, logN)
+		}
+		for i := range pol.Moduli {
+			for k, idx := range index {
+				res.Coefs[i][k] = pol.Coefs[i][idx]
+			}
+		}
+		return res
+	}
+
+	for i, q := range pol.Moduli {
+		for j, c := range pol.Coefs[i] {
+			k := j * g % M
+			if k < N {
+				res.Coefs[i][k] = c
+			} else { // X^N = -1
+				res.Coefs[i][k-N] = subMod(0, c, q)
+			}
+		}
+	}
+	return res
+}
+
+// retourne la représentation RNS dans la base <moduli>, en forme coefficients, du poly.Poly <pol>
+// de degré < <N>, dont les coefficients peuvent être négatifs
+func ToRNS(pol Poly, N int, moduli []uint64) RNSPoly {
+	res := NewRNSPoly(N, moduli)
+	r := new(big.Int)
+	for j, c := range pol.Coefs {
+		if j >= N {
+			break
+		}
+		if c.IsInt64() { // cas le plus courant : petits coefficients (erreurs, clés, plaintexts)
+			v := c.Int64()
+			for i, q := range moduli {
+				res.Coefs[i][j] = reduceInt64(v, q)
+			}
+			continue
+		}
+		for i, q := range moduli {
+			res.Coefs[i][j] = r.Mod(c, new(big.Int).SetUint64(q)).Uint64()
+		}
+	}
+	return res
+}
+
+// retourne le poly.Poly dont les coefficients sont les représentants centrés dans ]-Q/2, Q/2]
+// des coefficients du reciever (reconstruction par le théorème chinois)
+func (pol *RNSPoly) ToPoly() Poly {
+	if pol.IsNTT {
+		coefPol := INTT(*pol)
+		return coefPol.ToPoly()
+	}
+	Q := pol.Modulus()
+	halfQ := new(big.Int).Rsh(Q, 1)
+
+	// c = sum_i [c_i * (Q/q_i)^-1]_q_i * Q/q_i mod Q
+	qHat := make([]*big.Int, len(pol.Moduli))
+	qHatInv := make([]uint64, len(pol.Moduli))
+	for i, q := range pol.Moduli {
+		bq := new(big.Int).SetUint64(q)
+		qHat[i] = new(big.Int).Quo(Q, bq)
+		qHatInv[i] = new(big.Int).ModInverse(new(big.Int).Mod(qHat[i], bq), bq).Uint64()
+	}
+
+	N := pol.N()
+	coefs := make([]*big.Int, N)
+	tmp := new(big.Int)
+	for j := 0; j < N; j++ {
+		c := new(big.Int)
+		for i, q := range pol.Moduli {
+			tmp.SetUint64(mulMod(pol.Coefs[i][j], qHatInv[i], q))
+			c.Add(c, tmp.Mul(tmp, qHat[i]))
+		}
+		c.Mod(c, Q)
+		if c.Cmp(halfQ) == 1 {
+			c.Sub(c, Q)
+		}
+		coefs[j] = c
+	}
+	return NewPoly(coefs)
+}
+
+// retourne (approximativement) le polynôme <pol> dans la base <to>, par la conversion rapide
+// x -> sum_i [x_i * (Q/q_i)^-1]_q_i * Q/q_i. Le résultat est x + u*Q avec 0 <= u < l+1
+// le résultat est en forme coefficients (<pol> y est mis s'il est en forme NTT)
+func ConvertBasis(pol RNSPoly, to []uint64) RNSPoly {
+	pol = INTT(pol)
+	from := pol.Moduli
+	Q := ProdModuli(from)
+	N := pol.N()
+
+	qHatInv := make([]uint64, len(from))
+	qHatModP := make([][]uint64, len(from)) // (Q/q_i) mod p_k
+	r := new(big.Int)
+	for i, q := range from {
+		bq := new(big.Int).SetUint64(q)
+		qHat := new(big.Int).Quo(Q, bq)
+		qHatInv[i] = new(big.Int).ModInverse(r.Mod(qHat, bq), bq).Uint64()
+		qHatModP[i] = make([]uint64, len(to))
+		for k, p := range to {
+			qHatModP[i][k] = r.Mod(qHat, new(big.Int).SetUint64(p)).Uint64()
+		}
+	}
+
+	res := NewRNSPoly(N, to)
+	y := make([]uint64, len(from))
+	for j := 0; j < N; j++ {
+		for i, q := range from {
+			y[i] = mulMod(pol.Coefs[i][j], qHatInv[i], q)
+		}
+		for k, p := range to {
+			acc := uint64(0)
+			for i := range from {
+				acc = addMod(acc, mulMod(y[i]%p, qHatModP[i][k], p), p)
+			}
+			res.Coefs[k][j] = acc
+		}
+	}
+	return res
+}
+
+// retourne round(<pol> / P), où P est le produit des <k> derniers premiers de la base de <pol>
+// le résultat est exprimé dans la base formée des premiers restants, sous la même forme que <pol>
+// k = 1 correspond au rescaling, k = #premiers spéciaux à la descente de module du key switching
+func DivRoundByLastModuli(pol RNSPoly, k int) RNSPoly {
+	n := len(pol.Moduli) - k
+	qPart := RNSPoly{Coefs: pol.Coefs[:n], Moduli: pol.Moduli[:n], IsNTT: pol.IsNTT}
+	pPart := INTT(RNSPoly{Coefs: pol.Coefs[n:], Moduli: pol.Moduli[n:], IsNTT: pol.IsNTT})
+
+	// round(x/P) = (x - r)/P où r = [x + P/2]_P - P/2 est le représentant centré de x modulo P
+	P := ProdModuli(pPart.Moduli)
+	halfP := new(big.Int).Rsh(P, 1)
+	r := new(big.Int)
+	for i, p := range pPart.Moduli {
+		h := r.Mod(halfP, new(big.Int).SetUint64(p)).Uint64()
+		for j, c := range pPart.Coefs[i] {
+			pPart.Coefs[i][j] = addMod(c, h, p)
+		}
+	}
+	var conv RNSPoly
+	if k == 1 { // conversion exacte depuis un seul premier
+		conv = NewRNSPoly(pol.N(), qPart.Moduli)
+		for i, q := range qPart.Moduli {
+			for j, c := range pPart.Coefs[0] {
+				conv.Coefs[i][j] = c % q
+			}
+		}
+	} else {
+		conv = ConvertBasis(pPart, qPart.Moduli)
+	}
+	for i, q := range conv.Moduli {
+		h := r.Mod(halfP, new(big.Int).SetUint64(q)).Uint64()
+		for j, c := range conv.Coefs[i] {
+			conv.Coefs[i][j] = subMod(c, h, q)
+		}
+	}
+	if pol.IsNTT {
+		conv = NTT(conv)
+	}
+
+	res := NewRNSPoly(pol.N(), qPart.Moduli)
+	res.IsNTT = pol.IsNTT
+	for i, q := range qPart.Moduli {
+		bq := new(big.Int).SetUint64(q)
+		pInv := new(big.Int).ModInverse(r.Mod(P, bq), bq).Uint64()
+		for j, c := range qPart.Coefs[i] {
+			res.Coefs[i][j] = mulMod(subMod(c, conv.Coefs[i][j], q), pInv, q)
+		}
+	}
+	return res
+}
+
+// retourne <count> premiers distincts de <nbBits> bits chacun, congrus à 1 modulo 2<N> (NTT-friendly),
+// choisis en descendant depuis 2^nbBits en évitant les premiers de <exclude>
+// ErrInvalidArgument si <nbBits> n'est pas entre 2 et 61, si <N> n'est pas une puissance de 2
+// ou s'il n'y a pas assez de premiers de cette taille
+func GeneratePrimes(nbBits, N, count int, exclude []uint64) ([]uint64, error) {
+	if nbBits < 2 || nbBits > 61 {
+		return nil, fmt.Errorf("%w : primes must have between 2 and 61 bits, got %d", ErrInvalidArgument, nbBits)
+	}
+	if N < 1 || N&(N-1) != 0 {
+		return nil, fmt.Errorf("%w : N must be a power of 2, got %d", ErrInvalidArgument, N)
+	}
+	used := make(map[uint64]bool)
+	for _, q := range exclude {
+		used[q] = true
+	}
+	primes := []uint64{}
+	min := uint64(1) << (nbBits - 1)
+	step := uint64(2 * N)
+	for cand := uint64(1)<<nbBits + 1 - step; len(primes) < count; cand -= step {
+		if cand < min || cand > 1<<nbBits { // cand > 2^nbBits si la soustraction a débordé
+			return nil, fmt.Errorf("%w : not enough primes of %d bits congruent to 1 mod %d", ErrInvalidArgument, nbBits, 2*N)
+		}
+		if !used[cand] && new(big.Int).SetUint64(cand).ProbablyPrime(20) {
+			primes = append(primes, cand)
+		}
+	}
+	return primes, nil
+}
+
+/* ---------------- arithmétique modulaire sur un mot machine ---------------- */
+
+// retourne a + b mod q pour a, b < q
+func addMod(a, b, q uint64) uint64 {
+	c := a + b
+	if c >= q {
+		c -= q
+	}
+	return c
+}
+
+// retourne a - b mod q pour a, b < q
+func subMod(a, b, q uint64) uint64 {
+	if a >= b {
+		return a - b
+	}
+	return a + q - b
+}
+
+// retourne a * b mod q pour a, b < q
+func mulMod(a, b, q uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, q)
+}
+
+// retourne le représentant dans [0, q[ de l'entier signé v
+func reduceInt64(v int64, q uint64) uint64 {
+	if v >= 0 {
+		return uint64(v) % q
+	}
+	r := uint64(-v) % q
+	if r == 0 {
+		return 0
+	}
+	return q - r
+}
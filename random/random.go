@@ -1,9 +1,14 @@
 package random
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
+	"encoding/binary"
+	"io"
 	"math"
 	"math/big"
+	"math/bits"
 	mathrand "math/rand"
 
 	"kazat.ch/lbcrypto/poly"
@@ -18,6 +23,36 @@ func RandomPol(N int, Q *big.Int) poly.Poly {
 	return poly.NewPoly(coefs)
 }
 
+//Returns a poly.RNSPoly of deg < N, uniform modulo each prime of <moduli> (thus uniform modulo their product)
+func RandomRNSPol(N int, moduli []uint64) poly.RNSPoly {
+	return RandomRNSPolFrom(N, moduli, rand.Reader)
+}
+
+// comme RandomRNSPol, les octets aléatoires étant lus dans <r>
+// les limbs sont tirés dans l'ordre de <moduli> : avec un flux déterministe (NewPRNG), le polynôme obtenu
+// sur les k premiers premiers est la restriction de celui obtenu sur tous
+func RandomRNSPolFrom(N int, moduli []uint64, r io.Reader) poly.RNSPoly {
+	pol := poly.NewRNSPoly(N, moduli)
+	buf := make([]byte, 8*N)
+	for i, q := range moduli {
+		mask := uint64(1)<<uint(64-bits.LeadingZeros64(q)) - 1
+		j := 0
+		for j < N {
+			if _, err := io.ReadFull(r, buf); err != nil {
+				panic("Error : cannot read random bytes : " + err.Error())
+			}
+			for k := 0; k < N && j < N; k++ {
+				// rejection sampling : pas de biais modulo q
+				if c := binary.LittleEndian.Uint64(buf[8*k:]) & mask; c < q {
+					pol.Coefs[i][j] = c
+					j++
+				}
+			}
+		}
+	}
+	return pol
+}
+
 //Returns a []*big.Int of lenght N in {-1, 0, 1}^N with h non zero coordinates
 func Hwt(N, h int) []*big.Int {
 
@@ -44,11 +79,20 @@ func Hwt(N, h int) []*big.Int {
 
 //returns a []*big.Int of lenght <N>, each entry being a sample from ZO(r) with 0 < <r> < 1
 func ZO(N int, r float64) []*big.Int {
+	return zo(N, r, mathrand.Float64)
+}
+
+// comme ZO, les octets aléatoires étant lus dans <src> (voir DGFrom)
+func ZOFrom(N int, r float64, src io.Reader) []*big.Int {
+	return zo(N, r, mathrand.New(readerSource{src}).Float64)
+}
+
+func zo(N int, r float64, float64n func() float64) []*big.Int {
 	res := make([]*big.Int, N)
 
 	//remplissage avec des -1, 0, 1 suivant la distribution ZO(r)
 	for i := 0; i < N; i++ {
-		rand := mathrand.Float64()
+		rand := float64n()
 		if rand < r/2 {
 			res[i] = big.NewInt(-1)
 		} else if rand < r {
@@ -62,6 +106,16 @@ func ZO(N int, r float64) []*big.Int {
 
 //returns a []*big.Int of length <N>, each entry being a sample from DG(sig^2)
 func DG(N int, s2 float64, max *big.Int) []*big.Int {
+	return dg(N, s2, max, mathrand.NormFloat64)
+}
+
+// comme DG, les octets aléatoires étant lus dans <src> (rand.Reader ou un flux NewPRNG(NewSeed()) pour un tirage
+// imprévisible, le générateur de math/rand ne l'étant pas)
+func DGFrom(N int, s2 float64, max *big.Int, src io.Reader) []*big.Int {
+	return dg(N, s2, max, mathrand.New(readerSource{src}).NormFloat64)
+}
+
+func dg(N int, s2 float64, max *big.Int, normFloat64 func() float64) []*big.Int {
 	res := make([]*big.Int, N)
 	var rand float64
 	var randBigInt *big.Int
@@ -70,12 +124,77 @@ func DG(N int, s2 float64, max *big.Int) []*big.Int {
 	goodSamples := 0
 	for goodSamples < N {
 
-		rand = mathrand.NormFloat64() * math.Sqrt(s2)
+		rand = normFloat64() * math.Sqrt(s2)
 		randBigInt = big.NewInt(int64(math.Round(rand)))
 		if min.Cmp(randBigInt) == -1 && randBigInt.Cmp(max) == -1 {
 			res[goodSamples] = randBigInt
+			goodSamples++
 		}
-		goodSamples++
 	}
 	return res
 }
+
+// source de math/rand dont les tirages sont lus dans un io.Reader : les distributions de math/rand
+// (NormFloat64, Float64) sont alors aussi imprévisibles que le flux lu
+type readerSource struct {
+	r io.Reader
+}
+
+func (src readerSource) Uint64() uint64 {
+	var buf [8]byte
+	if _, err := io.ReadFull(src.r, buf[:]); err != nil {
+		panic("Error : cannot read random bytes : " + err.Error())
+	}
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func (src readerSource) Int63() int64 {
+	return int64(src.Uint64() >> 1)
+}
+
+// la source n'a pas d'état propre : Seed est sans effet
+func (src readerSource) Seed(int64) {}
+
+// taille en octets des graines des flux pseudo-aléatoires
+const SeedSize = 32
+
+// retourne une graine uniforme de SeedSize octets
+func NewSeed() []byte {
+	seed := make([]byte, SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		panic("Error : cannot read random bytes : " + err.Error())
+	}
+	return seed
+}
+
+// Flux pseudo-aléatoire déterministe dérivé d'une graine : AES-256 en mode compteur, la clé étant la graine
+// et le bloc compteur initial prngCounter (incrémenté comme un entier big-endian de 128 bits, voir cipher.NewCTR)
+// deux flux de même graine produisent les mêmes octets, ce qui permet de transmettre la graine
+// à la place des données qui en sont tirées : ce flux fait partie du format de sérialisation (voir poly.FormatVersion),
+// le changer demande une nouvelle version du format
+// Le compteur initial étant fixe, seule la graine sépare deux flux : une graine ne doit servir qu'à un seul
+// polynôme (les graines sont tirées par NewSeed, ou dérivées par haché avec un libellé et un indice distincts)
+type PRNG struct {
+	stream cipher.Stream
+}
+
+// bloc compteur initial de tous les flux : nul
+var prngCounter = [aes.BlockSize]byte{}
+
+// retourne le flux dérivé de la graine <seed> de SeedSize octets
+func NewPRNG(seed []byte) *PRNG {
+	if len(seed) != SeedSize {
+		panic("Error : the seed must have 32 bytes")
+	}
+	block, _ := aes.NewCipher(seed)
+	return &PRNG{stream: cipher.NewCTR(block, prngCounter[:])}
+}
+
+// remplit <p> avec les octets suivants du flux
+func (prng *PRNG) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	prng.stream.XORKeyStream(p, p)
+	return len(p), nil
+}